@@ -74,8 +74,9 @@ func (c *cmswww) logAdminUserActionLock(adminUser, user *database.User, action s
 // logAdminInvoiceAction logs an admin action on an invoice.
 //
 // This function must be called WITH the mutex held.
-func (c *cmswww) logAdminInvoiceAction(adminUser *database.User, token, action string) error {
-	return c.logAdminAction(adminUser, fmt.Sprintf("%v,%v", action, token))
+func (c *cmswww) logAdminInvoiceAction(adminUser *database.User, token, action string, reasonForAction string) error {
+	return c.logAdminAction(adminUser, fmt.Sprintf("%v,%v,%v", action, token,
+		reasonForAction))
 }
 
 // HandleInviteNewUser creates a new user in the db if it doesn't already
@@ -157,7 +158,9 @@ func (c *cmswww) HandleUserDetails(
 	ud := req.(*v1.UserDetails)
 
 	// Fetch the database user.
-	targetUser, err := c.findUser(ud.UserID, ud.Email, ud.Username, user.Admin)
+	canViewUsers := userHasPermission(user, permissionViewUsers)
+	targetUser, err := c.findUser(ud.UserID, ud.Email, ud.Username,
+		canViewUsers)
 	if err != nil {
 		return nil, err
 	}
@@ -166,9 +169,9 @@ func (c *cmswww) HandleUserDetails(
 	if targetUser == nil {
 		return &udr, nil
 	}
-	if !user.Admin && targetUser.ID != user.ID {
+	if !canViewUsers && targetUser.ID != user.ID {
 		// Don't return user details for another user unless the requesting
-		// user is an admin.
+		// user is an admin or has a role which allows it.
 		return &udr, nil
 	}
 
@@ -187,12 +190,20 @@ func (c *cmswww) HandleManageUser(
 	var mur v1.ManageUserReply
 
 	// Fetch the database user.
-	targetUser, err := c.findUser(mu.UserID, mu.Email, mu.Username,
-		adminUser.Admin)
+	targetUser, err := c.findUser(mu.UserID, mu.Email, mu.Username, true)
 	if err != nil {
 		return nil, err
 	}
 
+	// Only admins can manage admins and users with roles, so that user
+	// managers can't weaken the accounts of privileged users.
+	if isPrivilegedUser(targetUser) &&
+		!userHasPermission(adminUser, permissionAdmin) {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusMissingUserRole,
+		}
+	}
+
 	// Validate that the action is valid.
 	if mu.Action == v1.UserManageInvalid {
 		return nil, v1.UserError{
@@ -201,7 +212,7 @@ func (c *cmswww) HandleManageUser(
 	}
 
 	// Validate that the reason is supplied for certain actions.
-	if mu.Action == v1.UserManageLock {
+	if mu.Action == v1.UserManageLock || mu.Action == v1.UserManageResetTOTP {
 		mu.Reason = strings.TrimSpace(mu.Reason)
 		if len(mu.Reason) == 0 {
 			return nil, v1.UserError{
@@ -226,13 +237,23 @@ func (c *cmswww) HandleManageUser(
 			targetUser.UpdateIdentityVerificationExpiry = expiredTime
 		*/
 	case v1.UserManageUnlock:
-		targetUser.FailedLoginAttempts = 0
+		unlockUser(targetUser)
 		err = c.db.UpdateUser(targetUser)
 		if err != nil {
 			return nil, err
 		}
 	case v1.UserManageLock:
+		// Locks made by admins don't expire.
 		targetUser.FailedLoginAttempts = v1.LoginAttemptsToLockUser
+		targetUser.LockedUntil = 0
+		err = c.db.UpdateUser(targetUser)
+		if err != nil {
+			return nil, err
+		}
+	case v1.UserManageResetTOTP:
+		// This lets a user who lost their authenticator log in with
+		// their password alone, so that they can enroll again.
+		resetTOTP(targetUser)
 		err = c.db.UpdateUser(targetUser)
 		if err != nil {
 			return nil, err
@@ -252,6 +273,113 @@ func (c *cmswww) HandleManageUser(
 	return &mur, nil
 }
 
+// HandleSetUserHourlyRate sets the default hourly rate of a contractor, or
+// their hourly rate for a specific type of work.
+func (c *cmswww) HandleSetUserHourlyRate(
+	req interface{},
+	adminUser *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	sr := req.(*v1.SetUserHourlyRate)
+
+	// Fetch the database user.
+	targetUser, err := c.findUser(sr.UserID, sr.Email, sr.Username,
+		adminUser.Admin)
+	if err != nil {
+		return nil, err
+	}
+	if targetUser == nil {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusUserNotFound,
+		}
+	}
+
+	workType := normalizeWorkType(sr.WorkType)
+	if workType == "" {
+		targetUser.HourlyRate = sr.HourlyRate
+	} else if sr.HourlyRate == 0 {
+		delete(targetUser.WorkTypeHourlyRates, workType)
+	} else {
+		if targetUser.WorkTypeHourlyRates == nil {
+			targetUser.WorkTypeHourlyRates = make(map[string]uint64)
+		}
+		targetUser.WorkTypeHourlyRates[workType] = sr.HourlyRate
+	}
+
+	err = c.db.UpdateUser(targetUser)
+	if err != nil {
+		return nil, err
+	}
+
+	// Append this action to the admin log file.
+	action := fmt.Sprintf("set hourly rate to %v", sr.HourlyRate)
+	if workType != "" {
+		action += fmt.Sprintf(" for %v", workType)
+	}
+	err = c.logAdminUserActionLock(adminUser, targetUser, action, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.SetUserHourlyRateReply{
+		User: convertDatabaseUserToUser(targetUser),
+	}, nil
+}
+
+// HandleSetUserRole grants a role to a user or revokes it.
+func (c *cmswww) HandleSetUserRole(
+	req interface{},
+	adminUser *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	sr := req.(*v1.SetUserRole)
+
+	if _, ok := v1.UserRole[sr.Role]; !ok {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidUserRole,
+		}
+	}
+
+	// Fetch the database user.
+	targetUser, err := c.findUser(sr.UserID, sr.Email, sr.Username,
+		adminUser.Admin)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]v1.UserRoleT, 0, len(targetUser.Roles)+1)
+	for _, role := range targetUser.Roles {
+		if role != sr.Role {
+			roles = append(roles, role)
+		}
+	}
+	if !sr.Revoke {
+		roles = append(roles, sr.Role)
+	}
+	targetUser.Roles = roles
+
+	err = c.db.UpdateUser(targetUser)
+	if err != nil {
+		return nil, err
+	}
+
+	// Append this action to the admin log file.
+	action := fmt.Sprintf("grant role %v", v1.UserRole[sr.Role])
+	if sr.Revoke {
+		action = fmt.Sprintf("revoke role %v", v1.UserRole[sr.Role])
+	}
+	err = c.logAdminUserActionLock(adminUser, targetUser, action, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.SetUserRoleReply{
+		User: convertDatabaseUserToUser(targetUser),
+	}, nil
+}
+
 // resendInvite sets a new verification token and expiry for a new user;
 // the token must be verified before it expires.
 func (c *cmswww) resendInvite(adminUser, targetUser *database.User) (string, error) {
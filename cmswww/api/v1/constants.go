@@ -42,6 +42,60 @@ const (
 	// values for each line item in the CSV.
 	PolicyInvoiceFieldDelimiterChar rune = ','
 
+	// InvoiceFormatVersion is the current version of the JSON invoice
+	// format.
+	InvoiceFormatVersion = 1
+
+	// MIMETypeInvoiceCSV is the MIME type of invoices in the CSV format.
+	MIMETypeInvoiceCSV = "text/plain; charset=utf-8"
+
+	// MIMETypeInvoiceJSON is the MIME type of invoices in the JSON format.
+	MIMETypeInvoiceJSON = "application/json"
+
+	// PolicyInvoiceCurrency is the base currency, in which invoices are
+	// billed unless they specify another one, and to which the amounts of
+	// invoices in other currencies are normalized.
+	PolicyInvoiceCurrency = "USD"
+
+	// PolicyInvoiceCurrencyTag starts the comment which sets the currency
+	// of a CSV invoice, e.g. "# currency: EUR". It must come before the
+	// first line item.
+	PolicyInvoiceCurrencyTag = "currency:"
+
+	// PolicyMaxCommentLength is the maximum number of characters accepted
+	// for invoice comments.
+	PolicyMaxCommentLength = 8000
+
+	// PolicyMaxInvoiceAttachments is the maximum number of receipts that can
+	// be attached to an invoice.
+	PolicyMaxInvoiceAttachments = 20
+
+	// PolicyMaxInvoiceAttachmentSize is the maximum size, in bytes, of a
+	// receipt attached to an invoice.
+	PolicyMaxInvoiceAttachmentSize = 1024 * 1024
+
+	// PolicyMaxLineItemHours is the maximum number of hours of a single
+	// line item, which is the number of hours in a month.
+	PolicyMaxLineItemHours = 31 * 24
+
+	// PolicyMaxInvoiceAmount is the maximum hourly rate, amount and total
+	// cost of an invoice, in whole units of its currency.
+	PolicyMaxInvoiceAmount = 1000000000
+
+	// InvoiceLineItemTypeExpense is the type of work of line items which are
+	// expenses to be reimbursed rather than hours worked. Expenses have no
+	// hours and may reference a receipt attached to the invoice.
+	InvoiceLineItemTypeExpense = "Expense"
+
+	// PayoutBatchFormatJSON is the format of payout batches which are JSON
+	// documents; their sendtomany object can be passed as is to dcrwallet's
+	// sendtomany command.
+	PayoutBatchFormatJSON = "json"
+
+	// PayoutBatchFormatCSV is the format of payout batches which are CSV
+	// files with one payout per line.
+	PayoutBatchFormatCSV = "csv"
+
 	// ListPageSize is the maximum number of entries returned
 	// for the routes that return lists
 	ListPageSize = 25
@@ -49,6 +103,13 @@ const (
 	// LoginAttemptsToLockUser is the number of consecutive failed
 	// login attempts permitted before the system locks the user.
 	LoginAttemptsToLockUser = 5
+
+	// LoginChallengeSize is the size of a login challenge in bytes.
+	LoginChallengeSize = 32
+
+	// LoginChallengeExpiryTime is the amount of time during which a login
+	// challenge can be signed.
+	LoginChallengeExpiryTime = 5 * time.Minute
 )
 
 var (
@@ -64,7 +125,19 @@ var (
 		"A-z", "0-9", ".", ",", ":", ";", "-", " ", "@", "+",
 		"(", ")"}
 
+	// PolicyInvoiceCurrencies are the currencies in which invoices can be
+	// billed, as ISO 4217 codes.
+	PolicyInvoiceCurrencies = []string{PolicyInvoiceCurrency, "EUR"}
+
+	// PolicyInvoiceAttachmentMIMETypes are the MIME types accepted for
+	// receipts attached to an invoice.
+	PolicyInvoiceAttachmentMIMETypes = []string{
+		"image/png",
+		"application/pdf",
+	}
+
 	// InvoiceFields is the list of fields for each line item in an invoice.
+	// Trailing fields which aren't required may be omitted.
 	InvoiceFields = []InvoicePolicyField{
 		{
 			Name:     "Type of work",
@@ -92,9 +165,24 @@ var (
 			Required: true,
 		},
 		{
-			Name:     "Total cost (in USD)",
+			Name:     "Total cost (in the invoice currency)",
 			Type:     InvoiceFieldTypeUint,
-			Required: true,
+			Required: false,
+		},
+		{
+			Name:     "Receipt",
+			Type:     InvoiceFieldTypeString,
+			Required: false,
+		},
+		{
+			Name:     "Kind (hourly, fixed or milestone)",
+			Type:     InvoiceFieldTypeString,
+			Required: false,
+		},
+		{
+			Name:     "Milestone",
+			Type:     InvoiceFieldTypeString,
+			Required: false,
 		},
 	}
 )
@@ -4,6 +4,9 @@ type ErrorStatusT int
 type InvoiceStatusT int
 type UserManageActionT int
 type InvoiceFieldTypeT int
+type LineItemStatusT int
+type LineItemKindT int
+type UserRoleT int
 
 const (
 	// Error status codes
@@ -35,14 +38,34 @@ const (
 	ErrorStatusMissingInvoiceFile             ErrorStatusT = 25
 	ErrorStatusUserAlreadyExists              ErrorStatusT = 26
 	ErrorStatusReasonNotProvided              ErrorStatusT = 27
+	ErrorStatusInvalidInvoiceVersion          ErrorStatusT = 28
+	ErrorStatusNoInvoiceChanges               ErrorStatusT = 29
+	ErrorStatusInvalidCommentLength           ErrorStatusT = 30
+	ErrorStatusCommentNotFound                ErrorStatusT = 31
+	ErrorStatusInvalidLineItem                ErrorStatusT = 32
+	ErrorStatusInvalidLineItemStatus          ErrorStatusT = 33
+	ErrorStatusMonthlyRateNotFound            ErrorStatusT = 34
+	ErrorStatusMonthlyRateLocked              ErrorStatusT = 35
+	ErrorStatusMonthlyRateNotLocked           ErrorStatusT = 36
+	ErrorStatusTOTPCodeRequired               ErrorStatusT = 37
+	ErrorStatusInvalidTOTPCode                ErrorStatusT = 38
+	ErrorStatusTOTPEnrollmentRequired         ErrorStatusT = 39
+	ErrorStatusTOTPAlreadyEnabled             ErrorStatusT = 40
+	ErrorStatusTOTPNotEnabled                 ErrorStatusT = 41
+	ErrorStatusInvalidLoginChallenge          ErrorStatusT = 42
+	ErrorStatusInvalidUserRole                ErrorStatusT = 43
+	ErrorStatusMissingUserRole                ErrorStatusT = 44
+	ErrorStatusInvoiceApprovedByPayer         ErrorStatusT = 45
+	ErrorStatusDuplicateInvoiceApproval       ErrorStatusT = 46
 
 	// Invoice status codes
-	InvoiceStatusInvalid     InvoiceStatusT = 0 // Invalid status
-	InvoiceStatusNotFound    InvoiceStatusT = 1 // Invoice not found
-	InvoiceStatusNotReviewed InvoiceStatusT = 2 // Invoice has not been reviewed
-	InvoiceStatusRejected    InvoiceStatusT = 3 // Invoice needs to be revised
-	InvoiceStatusApproved    InvoiceStatusT = 4 // Invoice has been approved
-	InvoiceStatusPaid        InvoiceStatusT = 5 // Invoice has been paid
+	InvoiceStatusInvalid       InvoiceStatusT = 0 // Invalid status
+	InvoiceStatusNotFound      InvoiceStatusT = 1 // Invoice not found
+	InvoiceStatusNotReviewed   InvoiceStatusT = 2 // Invoice has not been reviewed
+	InvoiceStatusRejected      InvoiceStatusT = 3 // Invoice needs to be revised
+	InvoiceStatusApproved      InvoiceStatusT = 4 // Invoice has been approved
+	InvoiceStatusPaid          InvoiceStatusT = 5 // Invoice has been paid
+	InvoiceStatusPartiallyPaid InvoiceStatusT = 6 // Less than the quoted amount has been received
 
 	// User manage actions
 	UserManageInvalid                              UserManageActionT = 0 // Invalid action type
@@ -50,10 +73,28 @@ const (
 	UserManageRegenerateUpdateIdentityVerification UserManageActionT = 2
 	UserManageUnlock                               UserManageActionT = 3
 	UserManageLock                                 UserManageActionT = 4
+	UserManageResetTOTP                            UserManageActionT = 5
 
 	InvoiceFieldTypeInvalid InvoiceFieldTypeT = 0
 	InvoiceFieldTypeString  InvoiceFieldTypeT = 1
 	InvoiceFieldTypeUint    InvoiceFieldTypeT = 2
+
+	// Line item review statuses
+	LineItemStatusUnreviewed LineItemStatusT = 0 // No decision has been made
+	LineItemStatusApproved   LineItemStatusT = 1 // Line item will be paid
+	LineItemStatusDisputed   LineItemStatusT = 2 // Line item will not be paid
+
+	// Line item kinds
+	LineItemKindHourly    LineItemKindT = 0 // Billed by the hour
+	LineItemKindFixed     LineItemKindT = 1 // Billed at a fixed price
+	LineItemKindMilestone LineItemKindT = 2 // Fixed price for a proposal milestone
+
+	// User roles, which grant part of the privileges of an admin
+	UserRoleInvalid     UserRoleT = 0 // Invalid role
+	UserRoleReviewer    UserRoleT = 1 // Approves and rejects invoices
+	UserRoleTreasurer   UserRoleT = 2 // Pays invoices, marks them paid and locks the DCR rates
+	UserRoleUserManager UserRoleT = 3 // Invites, locks and unlocks users
+	UserRoleAuditor     UserRoleT = 4 // Reads everything, changes nothing
 )
 
 var (
@@ -86,16 +127,59 @@ var (
 		ErrorStatusMissingInvoiceFile:             "invoice file is missing",
 		ErrorStatusUserAlreadyExists:              "user already exists",
 		ErrorStatusReasonNotProvided:              "reason for action not provided",
+		ErrorStatusInvalidInvoiceVersion:          "invalid invoice version",
+		ErrorStatusNoInvoiceChanges:               "invoice does not contain any changes",
+		ErrorStatusInvalidCommentLength:           "comment is empty or exceeds the maximum length",
+		ErrorStatusCommentNotFound:                "comment not found",
+		ErrorStatusInvalidLineItem:                "invalid line item",
+		ErrorStatusInvalidLineItemStatus:          "invalid line item status",
+		ErrorStatusMonthlyRateNotFound:            "no DCR rate has been fetched for the month in the currency",
+		ErrorStatusMonthlyRateLocked:              "the DCR rate of the month in the currency is locked",
+		ErrorStatusMonthlyRateNotLocked:           "the DCR rate of the month in the currency has not been locked",
+		ErrorStatusTOTPCodeRequired:               "two-factor authentication code required",
+		ErrorStatusInvalidTOTPCode:                "invalid two-factor authentication code",
+		ErrorStatusTOTPEnrollmentRequired:         "two-factor authentication must be enabled",
+		ErrorStatusTOTPAlreadyEnabled:             "two-factor authentication is already enabled",
+		ErrorStatusTOTPNotEnabled:                 "two-factor authentication is not enabled",
+		ErrorStatusInvalidLoginChallenge:          "login challenge is missing, expired or for another user",
+		ErrorStatusInvalidUserRole:                "invalid user role",
+		ErrorStatusMissingUserRole:                "the user doesn't have the role required for this action",
+		ErrorStatusInvoiceApprovedByPayer:         "an invoice can't be paid by a user who approved it",
+		ErrorStatusDuplicateInvoiceApproval:       "the invoice has already been approved by this user",
 	}
 
 	// InvoiceStatus converts propsal status codes to human readable text
 	InvoiceStatus = map[InvoiceStatusT]string{
-		InvoiceStatusInvalid:     "invalid invoice status",
-		InvoiceStatusNotFound:    "not found",
-		InvoiceStatusNotReviewed: "unreviewed",
-		InvoiceStatusRejected:    "rejected",
-		InvoiceStatusApproved:    "approved",
-		InvoiceStatusPaid:        "paid",
+		InvoiceStatusInvalid:       "invalid invoice status",
+		InvoiceStatusNotFound:      "not found",
+		InvoiceStatusNotReviewed:   "unreviewed",
+		InvoiceStatusRejected:      "rejected",
+		InvoiceStatusApproved:      "approved",
+		InvoiceStatusPaid:          "paid",
+		InvoiceStatusPartiallyPaid: "partially paid",
+	}
+
+	// LineItemStatus converts line item review statuses to human readable
+	// text.
+	LineItemStatus = map[LineItemStatusT]string{
+		LineItemStatusUnreviewed: "unreviewed",
+		LineItemStatusApproved:   "approved",
+		LineItemStatusDisputed:   "disputed",
+	}
+
+	// LineItemKind converts line item kinds to the names used in invoices.
+	LineItemKind = map[LineItemKindT]string{
+		LineItemKindHourly:    "hourly",
+		LineItemKindFixed:     "fixed",
+		LineItemKindMilestone: "milestone",
+	}
+
+	// UserRole converts user roles to the names used to assign them.
+	UserRole = map[UserRoleT]string{
+		UserRoleReviewer:    "reviewer",
+		UserRoleTreasurer:   "treasurer",
+		UserRoleUserManager: "usermanager",
+		UserRoleAuditor:     "auditor",
 	}
 
 	// UserManageAction converts user manage actions to human readable text
@@ -105,5 +189,6 @@ var (
 		UserManageRegenerateUpdateIdentityVerification: "regenerate update identity verification",
 		UserManageUnlock:                               "unlock user",
 		UserManageLock:                                 "lock user",
+		UserManageResetTOTP:                            "reset two-factor authentication",
 	}
 )
@@ -1,7 +1,9 @@
 package v1
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 )
 
 const (
@@ -15,16 +17,33 @@ const (
 	RouteChangePassword            = "/user/password/change"
 	RouteResetPassword             = "/user/password/reset"
 	RouteManageUser                = "/user/manage"
+	RouteSetUserHourlyRate         = "/user/rate"
+	RouteSetUserRole               = "/user/role"
 	RouteEditUser                  = "/user/edit"
 	RouteEditUserExtendedPublicKey = "/user/edit/xpublickey"
+	RouteEnrollTOTP                = "/user/totp/enroll"
+	RouteVerifyTOTP                = "/user/totp/verify"
+	RouteDisableTOTP               = "/user/totp/disable"
 	RouteLogin                     = "/login"
+	RouteLoginChallenge            = "/login/challenge"
+	RouteLoginSignature            = "/login/signature"
 	RouteLogout                    = "/logout"
 	RouteInvoices                  = "/invoices"
 	RouteReviewInvoices            = "/invoices/review"
 	RoutePayInvoices               = "/invoices/pay"
 	RouteSubmitInvoice             = "/invoice/submit"
+	RouteEditInvoice               = "/invoice/edit"
 	RouteInvoiceDetails            = "/invoice"
 	RouteSetInvoiceStatus          = "/invoice/setstatus"
+	RouteNewInvoiceComment         = "/invoice/comments/new"
+	RouteInvoiceComments           = "/invoice/comments"
+	RouteReviewInvoiceLineItem     = "/invoice/lineitems/review"
+	RouteReconcileInvoicePayment   = "/invoice/payment/reconcile"
+	RouteProposalSpending          = "/proposals/spending"
+	RouteSetProposalBudget         = "/proposals/budget"
+	RouteMonthlyRate               = "/rate"
+	RouteFetchMonthlyRate          = "/rate/fetch"
+	RouteLockMonthlyRate           = "/rate/lock"
 	RoutePolicy                    = "/policy"
 )
 
@@ -49,31 +68,97 @@ type File struct {
 // CensorshipRecord contains the proof that an invoice was accepted for review.
 // The proof is verifiable on the client side.
 //
-// The Merkle field contains the digest of the invoice file.
+// The Merkle field contains the merkle root of the digests of the invoice file
+// followed by its attachments, sorted by name; without attachments it's the
+// digest of the invoice file.
 // The Token field contains a random censorship token that is signed by the
 // server private key.  The token can be used on the client to verify the
 // authenticity of the CensorshipRecord.
 type CensorshipRecord struct {
 	Token     string `json:"token"`     // Censorship token
-	Merkle    string `json:"merkle"`    // Digest of invoice file
+	Merkle    string `json:"merkle"`    // Merkle root of the invoice files
 	Signature string `json:"signature"` // Server side signature of []byte(Merkle+Token)
 }
 
+// InvoiceInput is the structured JSON format of an invoice, submitted with
+// the MIME type application/json as an alternative to the CSV format.
+type InvoiceInput struct {
+	Version   uint                   `json:"version"`            // Version of the invoice format
+	Month     uint16                 `json:"month"`              // The month that this invoice applies to
+	Year      uint16                 `json:"year"`               // The year that this invoice applies to
+	Currency  string                 `json:"currency"`           // Currency of all amounts; defaults to PolicyInvoiceCurrency
+	LineItems []InvoiceInputLineItem `json:"lineitems"`          // Work performed
+	Expenses  []InvoiceInputExpense  `json:"expenses,omitempty"` // Expenses to be reimbursed
+}
+
+// InvoiceInputLineItem is a unit of work within a JSON invoice. The total
+// cost of hourly line items is Hours * Rate; fixed and milestone line items
+// are billed by Amount instead.
+type InvoiceInputLineItem struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype,omitempty"`
+	Description string `json:"description"`
+	Proposal    string `json:"proposal,omitempty"` // Politeia proposal token
+	Hours       uint64 `json:"hours"`
+	Rate        uint64 `json:"rate"`                // Hourly rate
+	Kind        string `json:"kind,omitempty"`      // Line item kind name; defaults to hourly
+	Amount      uint64 `json:"amount,omitempty"`    // Price of fixed and milestone line items
+	Milestone   string `json:"milestone,omitempty"` // Proposal milestone, for milestone line items
+}
+
+// InvoiceInputExpense is an expense to be reimbursed within a JSON invoice.
+type InvoiceInputExpense struct {
+	Description string `json:"description"`
+	Proposal    string `json:"proposal,omitempty"` // Politeia proposal token
+	Amount      uint64 `json:"amount"`
+	Receipt     string `json:"receipt,omitempty"` // Name of the receipt attachment
+}
+
 // InvoiceRecord is an entire invoice and its content.
 type InvoiceRecord struct {
-	Status    InvoiceStatusT `json:"status"`    // Current status of invoice
-	Timestamp int64          `json:"timestamp"` // Last update of invoice
-	Month     uint16         `json:"month"`     // The month that this invoice applies to
-	Year      uint16         `json:"year"`      // The year that this invoice applies to
-	UserID    string         `json:"userid"`    // ID of user who submitted invoice
-	Username  string         `json:"username"`  // Username of user who submitted invoice
-	PublicKey string         `json:"publickey"` // User's public key, used to verify signature.
-	Signature string         `json:"signature"` // Signature of file digest
-	File      *File          `json:"file"`      // Actual invoice file
+	Status    InvoiceStatusT  `json:"status"`    // Current status of invoice
+	Timestamp int64           `json:"timestamp"` // Last update of invoice
+	Month     uint16          `json:"month"`     // The month that this invoice applies to
+	Year      uint16          `json:"year"`      // The year that this invoice applies to
+	UserID    string          `json:"userid"`    // ID of user who submitted invoice
+	Username  string          `json:"username"`  // Username of user who submitted invoice
+	PublicKey string          `json:"publickey"` // User's public key, used to verify signature.
+	Signature string          `json:"signature"` // Signature of file digest
+	Version   uint64          `json:"version"`   // Version of the invoice, starting at 1
+	File      *File           `json:"file"`      // Actual invoice file
+	Changes   []InvoiceChange `json:"changes"`   // History of status changes
+
+	Attachments []File          `json:"attachments,omitempty"` // Receipts for expenses
+	Payments    []PaymentRecord `json:"payments,omitempty"`    // Payments made for the invoice
+
+	ApprovalsRequired uint     `json:"approvalsrequired"`   // Number of approvals required by the server
+	Approvers         []string `json:"approvers,omitempty"` // Public keys of the admins who approved the invoice
 
 	CensorshipRecord CensorshipRecord `json:"censorshiprecord"`
 }
 
+// PaymentRecord represents a payment for an invoice and the transactions
+// which have been detected on-chain for it.
+type PaymentRecord struct {
+	Address        string   `json:"address"`                  // Address the payment is sent to
+	Amount         uint64   `json:"amount"`                   // Expected amount in atoms
+	TxNotBefore    int64    `json:"txnotbefore"`              // Minimum timestamp of the payment tx
+	TxIDs          []string `json:"txids,omitempty"`          // Txs which sent funds to the address
+	Confirmations  uint64   `json:"confirmations,omitempty"`  // Fewest confirmations of the txs
+	AmountReceived uint64   `json:"amountreceived,omitempty"` // Total amount received by the address in atoms
+	DetectedAt     int64    `json:"detectedat,omitempty"`     // Time at which the last tx was detected
+}
+
+// InvoiceChange represents a change in an invoice's status.
+type InvoiceChange struct {
+	AdminPublicKey string         `json:"adminpublickey"`      // Public key of the admin who made the change, if any
+	NewStatus      InvoiceStatusT `json:"newstatus"`           // Status after the change
+	Reason         string         `json:"reason,omitempty"`    // Admin reason for the change
+	Timestamp      int64          `json:"timestamp"`           // Timestamp of the change
+	Signature      string         `json:"signature,omitempty"` // Admin signature of the change, if any
+	Approval       bool           `json:"approval,omitempty"`  // Whether the change records an approval
+}
+
 // UserError represents an error that is caused by something that the user
 // did (malformed input, bad timing, etc).
 type UserError struct {
@@ -191,10 +276,39 @@ type UserInvoicesReply struct {
 }
 
 // Login attempts to login the user.  Note that by necessity the password
-// travels in the clear.
+// travels in the clear; LoginSignature can be used instead to avoid sending
+// it. The TOTP code is only required for users who have enabled two-factor
+// authentication; if it's missing, the login fails with
+// ErrorStatusTOTPCodeRequired so that the client can ask for it.
 type Login struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	TOTPCode string `json:"totpcode"`
+}
+
+// LoginChallenge requests a challenge for logging in with a signature
+// instead of a password. The challenge is tied to the session, so the
+// LoginSignature request must be sent with the same session cookie.
+type LoginChallenge struct {
+	Email string `json:"email"`
+}
+
+// LoginChallengeReply returns a hex encoded random challenge, which expires
+// after LoginChallengeExpiryTime.
+type LoginChallengeReply struct {
+	Challenge string `json:"challenge"`
+	Expiry    int64  `json:"expiry"` // Unix timestamp of the expiry
+}
+
+// LoginSignature logs the user in with a signature of the challenge made
+// with their active identity. A challenge can only be used once. The TOTP
+// code is required the same way as for Login. The LoginReply is used to
+// reply to it.
+type LoginSignature struct {
+	Email     string `json:"email"`
+	PublicKey string `json:"publickey"` // Active public key of the user
+	Signature string `json:"signature"` // Signature of the challenge
+	TOTPCode  string `json:"totpcode"`
 }
 
 // LoginReply is used to reply to the Login command.
@@ -205,6 +319,9 @@ type LoginReply struct {
 	Username  string `json:"username"`  // Username
 	PublicKey string `json:"publickey"` // Active public key
 	LastLogin int64  `json:"lastlogin"` // Unix timestamp of last login date
+
+	TOTPEnabled bool        `json:"totpenabled"` // Set if two-factor authentication is enabled
+	Roles       []UserRoleT `json:"roles"`       // Roles granted to the user
 }
 
 // Logout attempts to log the user out.
@@ -213,13 +330,15 @@ type Logout struct{}
 // LogoutReply indicates whether the Logout command was success or not.
 type LogoutReply struct{}
 
-// SubmitInvoice attempts to submit a new invoice.
+// SubmitInvoice attempts to submit a new invoice, along with any receipts
+// for the expenses in it.
 type SubmitInvoice struct {
-	Month     uint16 `json:"month"`
-	Year      uint16 `json:"year"`
-	File      File   `json:"file"`      // Invoice file
-	PublicKey string `json:"publickey"` // Key used to verify signature
-	Signature string `json:"signature"` // Signature of file hash
+	Month       uint16 `json:"month"`
+	Year        uint16 `json:"year"`
+	File        File   `json:"file"`                  // Invoice file
+	Attachments []File `json:"attachments,omitempty"` // Receipts for expenses
+	PublicKey   string `json:"publickey"`             // Key used to verify signature
+	Signature   string `json:"signature"`             // Signature of merkle root of the files
 }
 
 // SubmitInvoiceReply is used to reply to the SubmitInvoice command.
@@ -227,9 +346,25 @@ type SubmitInvoiceReply struct {
 	CensorshipRecord CensorshipRecord `json:"censorshiprecord"`
 }
 
-// InvoiceDetails is used to retrieve an invoice.
+// EditInvoice attempts to submit a new version of a rejected invoice.
+type EditInvoice struct {
+	Token       string `json:"token"`
+	File        File   `json:"file"`                  // Revised invoice file
+	Attachments []File `json:"attachments,omitempty"` // Receipts for expenses; replace the existing ones
+	PublicKey   string `json:"publickey"`             // Key used to verify signature
+	Signature   string `json:"signature"`             // Signature of merkle root of the files
+}
+
+// EditInvoiceReply is used to reply to the EditInvoice command.
+type EditInvoiceReply struct {
+	Invoice InvoiceRecord `json:"invoice"`
+}
+
+// InvoiceDetails is used to retrieve an invoice. If the version is not
+// provided, the latest version of the invoice is returned.
 type InvoiceDetails struct {
-	Token string `json:"token"`
+	Token   string `json:"token"`
+	Version uint64 `json:"version"`
 }
 
 // InvoiceDetailsReply is used to reply to an invoice details command.
@@ -237,11 +372,13 @@ type InvoiceDetailsReply struct {
 	Invoice InvoiceRecord `json:"invoice"`
 }
 
-// SetInvoiceStatus is used to approve or reject an unreviewed invoice.
+// SetInvoiceStatus is used to approve or reject an unreviewed invoice. A
+// reason must be provided when rejecting an invoice.
 type SetInvoiceStatus struct {
 	Token     string         `json:"token"`
 	Status    InvoiceStatusT `json:"status"`
-	Signature string         `json:"signature"` // Signature of Token+string(InvoiceStatus)
+	Reason    string         `json:"reason"`    // Admin reason for the status change
+	Signature string         `json:"signature"` // Signature of Token+string(InvoiceStatus)+Reason
 	PublicKey string         `json:"publickey"` // Public key of admin
 }
 
@@ -250,9 +387,64 @@ type SetInvoiceStatusReply struct {
 	Invoice InvoiceRecord `json:"invoice"`
 }
 
+// ReconcileInvoicePayment is used to mark a partially paid invoice as paid,
+// accepting the amount that has been received. A reason must be provided.
+type ReconcileInvoicePayment struct {
+	Token     string `json:"token"`
+	Reason    string `json:"reason"`    // Admin reason for accepting the payment
+	Signature string `json:"signature"` // Signature of Token+Reason
+	PublicKey string `json:"publickey"` // Public key of admin
+}
+
+// ReconcileInvoicePaymentReply is used to reply to a ReconcileInvoicePayment
+// command.
+type ReconcileInvoicePaymentReply struct {
+	Invoice InvoiceRecord `json:"invoice"`
+}
+
+// InvoiceComment is a comment left on an invoice by either the invoice's
+// owner or an admin.
+type InvoiceComment struct {
+	Token     string `json:"token"`     // Invoice token
+	CommentID uint64 `json:"commentid"` // Comment ID, unique per invoice and starting at 1
+	ParentID  uint64 `json:"parentid"`  // ID of the comment being replied to; 0 if none
+	Comment   string `json:"comment"`   // Comment text
+	UserID    string `json:"userid"`    // ID of the commenter
+	Username  string `json:"username"`  // Username of the commenter
+	PublicKey string `json:"publickey"` // Public key used to sign the comment
+	Signature string `json:"signature"` // Signature of Token+string(ParentID)+Comment
+	Timestamp int64  `json:"timestamp"` // Timestamp of the comment
+}
+
+// NewInvoiceComment is used to comment on an invoice. Only the owner of the
+// invoice and admins are allowed to comment on it.
+type NewInvoiceComment struct {
+	Token     string `json:"token"`     // Invoice token
+	ParentID  uint64 `json:"parentid"`  // ID of the comment being replied to; 0 if none
+	Comment   string `json:"comment"`   // Comment text
+	Signature string `json:"signature"` // Signature of Token+string(ParentID)+Comment
+	PublicKey string `json:"publickey"` // Public key of commenter
+}
+
+// NewInvoiceCommentReply is used to reply to a NewInvoiceComment command.
+type NewInvoiceCommentReply struct {
+	Comment InvoiceComment `json:"comment"`
+}
+
+// InvoiceComments retrieves all comments on an invoice.
+type InvoiceComments struct {
+	Token string `json:"token"`
+}
+
+// InvoiceCommentsReply is used to reply to an InvoiceComments command.
+type InvoiceCommentsReply struct {
+	Comments []InvoiceComment `json:"comments"`
+}
+
 // Invoices retrieves all invoices with a given status for a given month & year.
 //
-// Note: This call requires admin privileges.
+// Note: This call requires admin privileges or the reviewer, treasurer or
+// auditor role.
 type Invoices struct {
 	Status InvoiceStatusT `json:"status"`
 	Month  uint16         `json:"month"`
@@ -265,17 +457,38 @@ type InvoicesReply struct {
 }
 
 // ReviewInvoices retrieves all unreviewed invoices and returns each of their
-// line items along with their total costs in USD.
+// line items along with their total costs, both in the invoice currency and
+// normalized to USD. Amounts in other currencies are normalized using the
+// rates stored for the month, whether or not they're locked.
 //
-// Note: This call requires admin privileges.
+// Note: This call requires admin privileges or the reviewer, treasurer or
+// auditor role.
 type ReviewInvoices struct {
 	Month uint16 `json:"month"`
 	Year  uint16 `json:"year"`
 }
 
-// ReviewInvoicesReply is used to reply with a list of invoices.
+// ReviewInvoicesReply is used to reply with a list of invoices, along with
+// the spend per proposal across all of them.
 type ReviewInvoicesReply struct {
-	Invoices []InvoiceReview `json:"invoices"`
+	Invoices     []InvoiceReview        `json:"invoices"`
+	Proposals    []InvoiceProposalTotal `json:"proposals"`
+	TotalCostUSD uint64                 `json:"totalcostusd"`           // Total cost of the invoices which could be normalized
+	MissingRates []string               `json:"missingrates,omitempty"` // Currencies which couldn't be normalized for lack of a rate
+}
+
+// InvoiceProposalTotal is the spend billed against a Politeia proposal.
+// OverBudget is set when approving the spend would push the proposal over
+// its budget.
+type InvoiceProposalTotal struct {
+	Proposal     string   `json:"proposal"` // Politeia proposal token
+	TotalHours   uint64   `json:"totalhours"`
+	TotalCostUSD uint64   `json:"totalcostusd"`
+	Milestones   []string `json:"milestones,omitempty"` // Milestones billed
+	BudgetUSD    uint64   `json:"budgetusd,omitempty"`  // Budget of the proposal, if set
+	SpentUSD     uint64   `json:"spentusd,omitempty"`   // Approved and paid spend across all invoices
+	OverBudget   bool     `json:"overbudget"`
+	SpendUnknown bool     `json:"spendunknown,omitempty"` // Whether some of SpentUSD is left out for lack of a rate
 }
 
 // InvoiceReview represents a submitted invoice which needs to be reviewed.
@@ -286,44 +499,247 @@ type InvoiceReview struct {
 	LineItems      []InvoiceReviewLineItem `json:"lineitems"`
 	PaymentAddress string                  `json:"paymentaddress"`
 	TotalHours     uint64                  `json:"totalhours"`
-	TotalCostUSD   uint64                  `json:"totalcostusd"`
+	Currency       string                  `json:"currency"`     // Currency in which the invoice is billed
+	TotalCost      uint64                  `json:"totalcost"`    // Total cost in the invoice currency
+	TotalCostUSD   uint64                  `json:"totalcostusd"` // Total cost normalized to USD; 0 if there's no rate to normalize it with
+	CostMismatch   bool                    `json:"costmismatch"` // Whether any line item's cost differs from its expected cost
+	Proposals      []InvoiceProposalTotal  `json:"proposals"`    // Spend per proposal, normalized to USD
 }
 
 // InvoiceReviewLineItem is a unit of work within a submitted invoice.
 type InvoiceReviewLineItem struct {
-	Type        string `json:"type"`
-	Subtype     string `json:"subtype"`
-	Description string `json:"description"`
-	Proposal    string `json:"proposal"`
-	Hours       uint64 `json:"hours"`
-	TotalCost   uint64 `json:"totalcost"`
+	LineNumber   uint64          `json:"linenumber"` // Position in the invoice, starting at 1
+	Kind         LineItemKindT   `json:"kind"`
+	Type         string          `json:"type"`
+	Subtype      string          `json:"subtype"`
+	Description  string          `json:"description"`
+	Proposal     string          `json:"proposal"`
+	Hours        uint64          `json:"hours"`
+	TotalCost    uint64          `json:"totalcost"`
+	Receipt      string          `json:"receipt,omitempty"`      // Name of the receipt attachment, for expenses
+	Milestone    string          `json:"milestone,omitempty"`    // Proposal milestone, for milestone line items
+	Rate         uint64          `json:"rate,omitempty"`         // Contractor's hourly rate for this type of work, if set
+	ExpectedCost uint64          `json:"expectedcost,omitempty"` // Hours * Rate, if the rate is set
+	CostMismatch bool            `json:"costmismatch"`           // Whether the total cost differs from the expected cost
+	Status       LineItemStatusT `json:"status"`                 // Admin review decision
+	Note         string          `json:"note,omitempty"`         // Admin note for the decision
+}
+
+// ReviewInvoiceLineItem is used to approve or dispute a single line item
+// within an invoice. A note must be provided when disputing a line item.
+// Decisions only apply to the invoice version they were made on.
+//
+// Note: This call requires admin privileges or the reviewer role.
+type ReviewInvoiceLineItem struct {
+	Token      string          `json:"token"`
+	LineNumber uint64          `json:"linenumber"`
+	Status     LineItemStatusT `json:"status"`
+	Note       string          `json:"note"`
+	Signature  string          `json:"signature"` // Signature of Token+string(LineNumber)+string(Status)+Note
+	PublicKey  string          `json:"publickey"` // Public key of admin
 }
 
-// PayInvoices retrieves all approved invoices and returns them
-// along with their amounts in DCR, using the provided DCR-USD rate.
+// ReviewInvoiceLineItemReply is used to reply to a ReviewInvoiceLineItem
+// command.
+type ReviewInvoiceLineItemReply struct {
+	Invoice InvoiceReview `json:"invoice"`
+}
+
+// ProposalSpending is used to retrieve the spend billed against proposals
+// across all approved and paid invoices. If no proposal is provided, all
+// proposals which have a budget or have been billed are returned.
+//
+// Note: This call requires admin privileges or the reviewer, treasurer or
+// auditor role.
+type ProposalSpending struct {
+	Proposal string `json:"proposal"` // Politeia proposal token
+}
+
+// ProposalSpendingReply is used to reply to the ProposalSpending command.
+type ProposalSpendingReply struct {
+	Proposals []ProposalSpend `json:"proposals"`
+}
+
+// ProposalSpend is the spend billed against a Politeia proposal, excluding
+// disputed line items.
+type ProposalSpend struct {
+	Proposal     string `json:"proposal"`               // Politeia proposal token
+	BudgetUSD    uint64 `json:"budgetusd"`              // Budget of the proposal; 0 if not set
+	ApprovedUSD  uint64 `json:"approvedusd"`            // Billed in approved invoices, not yet paid
+	PaidUSD      uint64 `json:"paidusd"`                // Billed in paid invoices
+	OverBudget   bool   `json:"overbudget"`             // Whether the spend exceeds the budget
+	SpendUnknown bool   `json:"spendunknown,omitempty"` // Whether some spend is left out for lack of a rate to normalize it
+}
+
+// SetProposalBudget sets the maximum amount that can be billed against a
+// Politeia proposal; a budget of 0 removes it.
 //
 // Note: This call requires admin privileges.
+type SetProposalBudget struct {
+	Proposal  string `json:"proposal"`  // Politeia proposal token
+	BudgetUSD uint64 `json:"budgetusd"` // Budget in USD
+}
+
+// SetProposalBudgetReply is used to reply to the SetProposalBudget command.
+type SetProposalBudgetReply struct {
+	Proposal ProposalSpend `json:"proposal"`
+}
+
+// MonthlyRateRecord is the DCR rate in one of the supported currencies used
+// to pay the invoices of a month.
+type MonthlyRateRecord struct {
+	Month        uint16              `json:"month"`
+	Year         uint16              `json:"year"`
+	Currency     string              `json:"currency"`
+	DCRRateCents uint64              `json:"dcrratecents"` // Mean of the rates of the sources which aren't outliers, in cents of the currency per DCR
+	Sources      []MonthlyRateSource `json:"sources"`
+	Timestamp    int64               `json:"timestamp"`          // Time at which the rate was fetched
+	Locked       bool                `json:"locked"`             // Whether the rate has been agreed on
+	LockedBy     string              `json:"lockedby,omitempty"` // Public key of the admin who locked the rate
+	LockedAt     int64               `json:"lockedat,omitempty"`
+}
+
+// MonthlyRateSource is the rate provided by a single source, or the error
+// which prevented the source from providing one.
+type MonthlyRateSource struct {
+	Name         string `json:"name"`
+	DCRRateCents uint64 `json:"dcrratecents,omitempty"`
+	Outlier      bool   `json:"outlier,omitempty"` // Whether the rate was left out for deviating too much
+	Error        string `json:"error,omitempty"`
+}
+
+// MonthlyRate retrieves the stored DCR rate of a month in the given
+// currency, which defaults to USD.
+//
+// Note: This call requires admin privileges or the reviewer, treasurer or
+// auditor role.
+type MonthlyRate struct {
+	Month    uint16 `schema:"month"`
+	Year     uint16 `schema:"year"`
+	Currency string `schema:"currency"`
+}
+
+// MonthlyRateReply is used to reply to the MonthlyRate command.
+type MonthlyRateReply struct {
+	Rate MonthlyRateRecord `json:"rate"`
+}
+
+// FetchMonthlyRate computes the DCR rate of a month which has ended in the
+// given currency, which defaults to USD, from the average monthly prices of
+// the rate sources, and stores it. The rate of a month can be fetched again
+// until it's locked.
+//
+// Note: This call requires admin privileges or the treasurer role.
+type FetchMonthlyRate struct {
+	Month    uint16 `json:"month"`
+	Year     uint16 `json:"year"`
+	Currency string `json:"currency"`
+}
+
+// FetchMonthlyRateReply is used to reply to the FetchMonthlyRate command.
+type FetchMonthlyRateReply struct {
+	Rate MonthlyRateRecord `json:"rate"`
+}
+
+// LockMonthlyRate marks the stored DCR rate of a month in the given currency
+// as agreed on, so that it's used to pay the invoices of the month billed in
+// that currency. The rate is included so that the admin signs the rate which
+// is being locked.
+//
+// Note: This call requires admin privileges or the treasurer role.
+type LockMonthlyRate struct {
+	Month        uint16 `json:"month"`
+	Year         uint16 `json:"year"`
+	Currency     string `json:"currency"` // Defaults to USD
+	DCRRateCents uint64 `json:"dcrratecents"`
+	PublicKey    string `json:"publickey"` // Key used for signature
+	Signature    string `json:"signature"` // Signature of month+year+currency+dcrratecents
+}
+
+// LockMonthlyRateReply is used to reply to the LockMonthlyRate command.
+type LockMonthlyRateReply struct {
+	Rate MonthlyRateRecord `json:"rate"`
+}
+
+// PayInvoices retrieves all approved invoices and returns them
+// along with their amounts in DCR, using the provided DCR-USD rate. Line
+// items which have been disputed are not included in the amounts.
+//
+// Invoices billed in other currencies are quoted at the locked rate of the
+// month in their currency. The USD rate is always required, since it's used
+// to normalize their amounts to USD.
+//
+// Note: This call requires admin privileges or the treasurer role.
 type PayInvoices struct {
-	Month      uint16  `json:"month"`
-	Year       uint16  `json:"year"`
-	DCRUSDRate float64 `json:"dcrusdrate"`
+	Month           uint16 `json:"month"`
+	Year            uint16 `json:"year"`
+	DCRUSDRateCents uint64 `json:"dcrusdratecents"` // USD cents per DCR; defaults to the locked rate of the month
+	Requote         bool   `json:"requote"`         // Replace outstanding quotes with new ones
+
+	// BatchFormat is the format of the payout batch to return, if any.
+	BatchFormat string `json:"batchformat,omitempty"`
 }
 
 // PayInvoicesReply is used to reply with a list of invoices.
 type PayInvoicesReply struct {
-	Invoices []InvoicePayment `json:"invoices"`
+	Invoices       []InvoicePayment   `json:"invoices"`
+	TotalCostUSD   uint64             `json:"totalcostusd"`    // Total cost of the invoices normalized to USD
+	TotalCostAtoms uint64             `json:"totalcostatoms"`  // Total amount quoted for the invoices
+	Batch          *SignedPayoutBatch `json:"batch,omitempty"` // Only set if requested
+
+	// Tokens of the invoices which were skipped because they were approved
+	// by the admin paying them.
+	ApprovedByPayer []string `json:"approvedbypayer,omitempty"`
+}
+
+// PayoutBatch is the list of payouts for the invoices of a month, in a form
+// which can be consumed by a wallet.
+type PayoutBatch struct {
+	Month           uint16             `json:"month"`
+	Year            uint16             `json:"year"`
+	DCRUSDRateCents uint64             `json:"dcrusdratecents"` // Rate in USD cents per DCR used for new quotes
+	DCRRates        map[string]uint64  `json:"dcrrates"`        // Rate in cents per DCR used for new quotes, per currency
+	Timestamp       int64              `json:"timestamp"`       // Time at which the batch was created
+	Payouts         []Payout           `json:"payouts"`
+	SendToMany      map[string]float64 `json:"sendtomany"` // DCR amount per address, as accepted by dcrwallet's sendtomany
+}
+
+// Payout is a single payment of a payout batch.
+type Payout struct {
+	Address         string `json:"address"`
+	Atoms           uint64 `json:"atoms"`           // Amount to send in atoms
+	DCRUSDRateCents uint64 `json:"dcrusdratecents"` // Rate in USD cents per DCR at which the payment was quoted
+	Currency        string `json:"currency"`        // Currency in which the invoice is billed
+	DCRRateCents    uint64 `json:"dcrratecents"`    // Rate in cents of the invoice currency per DCR at which the payment was quoted
+	Token           string `json:"token"`           // Token of the invoice being paid
+	Username        string `json:"username"`
+}
+
+// SignedPayoutBatch is a payout batch file signed by the server, so that it
+// can't be tampered with before it reaches the wallet.
+type SignedPayoutBatch struct {
+	Format    string `json:"format"`    // Format of the payload
+	Payload   string `json:"payload"`   // Contents of the payout batch file
+	PublicKey string `json:"publickey"` // Public key of the server
+	Signature string `json:"signature"` // Signature of the payload
 }
 
 // InvoicePayment represents a submitted invoice which has been processed
 // and is ready for payment.
 type InvoicePayment struct {
-	UserID         string  `json:"userid"`
-	Username       string  `json:"username"`
-	Token          string  `json:"token"`
-	TotalHours     uint64  `json:"totalhours"`
-	TotalCostUSD   uint64  `json:"totalcostusd"`
-	TotalCostDCR   float64 `json:"totalcostdcr"`
-	PaymentAddress string  `json:"paymentaddress"`
+	UserID          string `json:"userid"`
+	Username        string `json:"username"`
+	Token           string `json:"token"`
+	TotalHours      uint64 `json:"totalhours"`
+	Currency        string `json:"currency"`     // Currency in which the invoice is billed
+	TotalCost       uint64 `json:"totalcost"`    // Total cost in the invoice currency
+	TotalCostUSD    uint64 `json:"totalcostusd"` // Total cost normalized to USD
+	TotalCostAtoms  uint64 `json:"totalcostatoms"`
+	DCRUSDRateCents uint64 `json:"dcrusdratecents"` // Rate in USD cents per DCR at which the payment was quoted
+	DCRRateCents    uint64 `json:"dcrratecents"`    // Rate in cents of the invoice currency per DCR at which the payment was quoted
+	PaymentAddress  string `json:"paymentaddress"`
+	QuotedAt        int64  `json:"quotedat"` // Time at which the payment was quoted
+	Existing        bool   `json:"existing"` // Whether this is an outstanding quote from a previous call
 }
 
 // MyInvoices retrieves all invoices with a given status for a user.
@@ -347,6 +763,36 @@ type ChangePassword struct {
 // is logged in.
 type ChangePasswordReply struct{}
 
+// EnrollTOTP generates a new TOTP secret for the logged in user. Two-factor
+// authentication isn't enabled until a code generated from the secret has
+// been verified with VerifyTOTP.
+type EnrollTOTP struct{}
+
+// EnrollTOTPReply returns the base32 encoded secret, along with an otpauth
+// URI which authenticator apps can import.
+type EnrollTOTPReply struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// VerifyTOTP enables two-factor authentication for the logged in user, given
+// a code generated from the secret returned by EnrollTOTP.
+type VerifyTOTP struct {
+	Code string `json:"code"`
+}
+
+// VerifyTOTPReply is used to reply to the VerifyTOTP command.
+type VerifyTOTPReply struct{}
+
+// DisableTOTP disables two-factor authentication for the logged in user,
+// given a current code.
+type DisableTOTP struct {
+	Code string `json:"code"`
+}
+
+// DisableTOTPReply is used to reply to the DisableTOTP command.
+type DisableTOTPReply struct{}
+
 // ResetPassword is used to perform a password change when the
 // user is not logged in.
 type ResetPassword struct {
@@ -373,7 +819,9 @@ type PolicyReply struct {
 	MaxUsernameLength      uint          `json:"maxusernamelength"`
 	UsernameSupportedChars []string      `json:"usernamesupportedchars"`
 	ListPageSize           uint          `json:"listpagesize"`
+	MaxCommentLength       uint          `json:"maxcommentlength"`
 	ValidMIMETypes         []string      `json:"validmimetypes"`
+	AdminTOTPRequired      bool          `json:"admintotprequired"` // Set if admins and users with roles must enable two-factor authentication
 	Invoice                InvoicePolicy `json:"invoice"`
 }
 
@@ -382,6 +830,15 @@ type InvoicePolicy struct {
 	FieldDelimiterChar rune                 `json:"fielddelimiterchar"`
 	CommentChar        rune                 `json:"commentchar"`
 	Fields             []InvoicePolicyField `json:"fields"`
+	MIMETypes          []string             `json:"mimetypes"`     // Supported invoice formats
+	FormatVersion      uint                 `json:"formatversion"` // Current JSON invoice format version
+	Currency           string               `json:"currency"`      // Base currency, to which other currencies are normalized
+	Currencies         []string             `json:"currencies"`    // Currencies in which invoices can be billed
+
+	LineItemKinds       []string `json:"lineitemkinds"`       // Names of the supported line item kinds
+	AttachmentMIMETypes []string `json:"attachmentmimetypes"` // Supported receipt formats
+	MaxAttachments      uint     `json:"maxattachments"`      // Maximum number of receipts per invoice
+	MaxAttachmentSize   uint     `json:"maxattachmentsize"`   // Maximum size of a receipt, in bytes
 }
 
 type InvoicePolicyField struct {
@@ -416,6 +873,41 @@ type ManageUserReply struct {
 	VerificationToken *string `json:"verificationtoken"` // Only set for certain user manage actions
 }
 
+// SetUserHourlyRate sets the hourly rate of a contractor given their id,
+// email or username. If a work type is provided, the rate only applies to
+// line items of that type of work. A rate of 0 removes the rate.
+//
+// Note: This call requires admin privileges.
+type SetUserHourlyRate struct {
+	UserID     string `json:"userid"`
+	Email      string `json:"email"`
+	Username   string `json:"username"`
+	WorkType   string `json:"worktype"`   // Optional type of work the rate applies to
+	HourlyRate uint64 `json:"hourlyrate"` // Hourly rate in USD
+}
+
+// SetUserHourlyRateReply is the reply for the SetUserHourlyRate command.
+type SetUserHourlyRateReply struct {
+	User User `json:"user"`
+}
+
+// SetUserRole grants a role to a user, or revokes it, given their id, email
+// or username.
+//
+// Note: This call requires admin privileges.
+type SetUserRole struct {
+	UserID   string    `json:"userid"`
+	Email    string    `json:"email"`
+	Username string    `json:"username"`
+	Role     UserRoleT `json:"role"`
+	Revoke   bool      `json:"revoke"` // Set to revoke the role instead of granting it
+}
+
+// SetUserRoleReply is the reply for the SetUserRole command.
+type SetUserRoleReply struct {
+	User User `json:"user"`
+}
+
 // EditUser allows a user to make changes to his profile.
 type EditUser struct {
 	Name     *string `json:"name"`
@@ -439,26 +931,31 @@ type EditUserExtendedPublicKeyReply struct {
 
 // User represents an individual user.
 type User struct {
-	ID                                        string          `json:"id"`
-	Email                                     string          `json:"email"`
-	Username                                  string          `json:"username"`
-	Name                                      string          `json:"name"`
-	Location                                  string          `json:"location"`
-	ExtendedPublicKey                         string          `json:"xpublickey"`
-	Admin                                     bool            `json:"isadmin"`
-	RegisterVerificationToken                 []byte          `json:"newuserverificationtoken"`
-	RegisterVerificationExpiry                int64           `json:"newuserverificationexpiry"`
-	UpdateIdentityVerificationToken           []byte          `json:"updateidentityverificationtoken"`
-	UpdateIdentityVerificationExpiry          int64           `json:"updateidentityverificationexpiry"`
-	ResetPasswordVerificationToken            []byte          `json:"resetpasswordverificationtoken"`
-	ResetPasswordVerificationExpiry           int64           `json:"resetpasswordverificationexpiry"`
-	UpdateExtendedPublicKeyVerificationToken  []byte          `json:"updatexpublickeyverificationtoken"`
-	UpdateExtendedPublicKeyVerificationExpiry int64           `json:"updatexpublickeyverificationexpiry"`
-	LastLogin                                 int64           `json:"lastlogin"`
-	FailedLoginAttempts                       uint64          `json:"failedloginattempts"`
-	Locked                                    bool            `json:"islocked"`
-	Identities                                []UserIdentity  `json:"identities"`
-	Invoices                                  []InvoiceRecord `json:"invoices"`
+	ID                                        string            `json:"id"`
+	Email                                     string            `json:"email"`
+	Username                                  string            `json:"username"`
+	Name                                      string            `json:"name"`
+	Location                                  string            `json:"location"`
+	ExtendedPublicKey                         string            `json:"xpublickey"`
+	Admin                                     bool              `json:"isadmin"`
+	RegisterVerificationToken                 []byte            `json:"newuserverificationtoken"`
+	RegisterVerificationExpiry                int64             `json:"newuserverificationexpiry"`
+	UpdateIdentityVerificationToken           []byte            `json:"updateidentityverificationtoken"`
+	UpdateIdentityVerificationExpiry          int64             `json:"updateidentityverificationexpiry"`
+	ResetPasswordVerificationToken            []byte            `json:"resetpasswordverificationtoken"`
+	ResetPasswordVerificationExpiry           int64             `json:"resetpasswordverificationexpiry"`
+	UpdateExtendedPublicKeyVerificationToken  []byte            `json:"updatexpublickeyverificationtoken"`
+	UpdateExtendedPublicKeyVerificationExpiry int64             `json:"updatexpublickeyverificationexpiry"`
+	LastLogin                                 int64             `json:"lastlogin"`
+	FailedLoginAttempts                       uint64            `json:"failedloginattempts"`
+	Locked                                    bool              `json:"islocked"`
+	LockedUntil                               int64             `json:"lockeduntil,omitempty"`         // Time at which the lock expires; 0 if it doesn't
+	HourlyRate                                uint64            `json:"hourlyrate"`                    // Default hourly rate in USD; 0 if not set
+	WorkTypeHourlyRates                       map[string]uint64 `json:"worktypehourlyrates,omitempty"` // Hourly rates in USD which override the default rate, keyed by type of work
+	TOTPEnabled                               bool              `json:"totpenabled"`                   // Set if two-factor authentication is enabled
+	Roles                                     []UserRoleT       `json:"roles"`                         // Roles granted to the user
+	Identities                                []UserIdentity    `json:"identities"`
+	Invoices                                  []InvoiceRecord   `json:"invoices"`
 }
 
 // UserIdentity represents a user's unique identity.
@@ -466,3 +963,43 @@ type UserIdentity struct {
 	PublicKey string `json:"publickey"`
 	Active    bool   `json:"isactive"`
 }
+
+// UnmarshalJSON decodes a PayInvoices command, converting the floating point
+// DCR/USD rate sent by older clients to USD cents per DCR.
+func (pi *PayInvoices) UnmarshalJSON(data []byte) error {
+	type payInvoices PayInvoices
+	var legacy struct {
+		payInvoices
+		DCRUSDRate float64 `json:"dcrusdrate"`
+	}
+	err := json.Unmarshal(data, &legacy)
+	if err != nil {
+		return err
+	}
+
+	*pi = PayInvoices(legacy.payInvoices)
+	if pi.DCRUSDRateCents == 0 && legacy.DCRUSDRate > 0 {
+		pi.DCRUSDRateCents = uint64(math.Round(legacy.DCRUSDRate * 100))
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes an InvoicePayment, converting the floating point DCR
+// amount sent by older servers to atoms.
+func (ip *InvoicePayment) UnmarshalJSON(data []byte) error {
+	type invoicePayment InvoicePayment
+	var legacy struct {
+		invoicePayment
+		TotalCostDCR float64 `json:"totalcostdcr"`
+	}
+	err := json.Unmarshal(data, &legacy)
+	if err != nil {
+		return err
+	}
+
+	*ip = InvoicePayment(legacy.invoicePayment)
+	if ip.TotalCostAtoms == 0 && legacy.TotalCostDCR > 0 {
+		ip.TotalCostAtoms = uint64(math.Round(legacy.TotalCostDCR * 1e8))
+	}
+	return nil
+}
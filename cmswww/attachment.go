@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+
+	"github.com/decred/dcrtime/merkle"
+	"github.com/decred/politeia/util"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+)
+
+// sortInvoiceAttachments sorts attachments by name, which is the order in
+// which they're included in the merkle root and stored in politeiad.
+func sortInvoiceAttachments(attachments []v1.File) {
+	sort.Slice(attachments, func(i, j int) bool {
+		return attachments[i].Name < attachments[j].Name
+	})
+}
+
+// invoiceMerkleRoot returns the merkle root of the digests of the invoice file
+// followed by its attachments, which is what the user signs. Without
+// attachments it's the digest of the invoice file. The attachments must
+// already be sorted.
+func invoiceMerkleRoot(file *v1.File, attachments []v1.File) (string, error) {
+	files := append([]v1.File{*file}, attachments...)
+	hashes := make([]*[sha256.Size]byte, 0, len(files))
+	for _, f := range files {
+		b, err := hex.DecodeString(f.Digest)
+		if err != nil || len(b) != sha256.Size {
+			return "", v1.UserError{
+				ErrorCode:    v1.ErrorStatusInvalidFileDigest,
+				ErrorContext: []string{f.Name},
+			}
+		}
+
+		var d [sha256.Size]byte
+		copy(d[:], b)
+		hashes = append(hashes, &d)
+	}
+
+	return hex.EncodeToString(merkle.Root(hashes)[:]), nil
+}
+
+// verifyFileDigest verifies that the digest of a file matches its payload,
+// and returns the decoded payload.
+func verifyFileDigest(file *v1.File) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(file.Payload)
+	if err != nil {
+		return nil, v1.UserError{
+			ErrorCode:    v1.ErrorStatusInvalidBase64,
+			ErrorContext: []string{file.Name},
+		}
+	}
+
+	if hex.EncodeToString(util.Digest(data)) != file.Digest {
+		return nil, v1.UserError{
+			ErrorCode:    v1.ErrorStatusInvalidFileDigest,
+			ErrorContext: []string{file.Name},
+		}
+	}
+
+	return data, nil
+}
+
+// validateInvoiceAttachments verifies the name, size, MIME type and digest of
+// each attachment, and returns the set of attachment names so that line items
+// can be checked against it.
+func validateInvoiceAttachments(attachments []v1.File) (map[string]bool, error) {
+	if len(attachments) > v1.PolicyMaxInvoiceAttachments {
+		return nil, invalidInvoiceInputError(fmt.Sprintf(
+			"an invoice can have at most %v attachments",
+			v1.PolicyMaxInvoiceAttachments))
+	}
+
+	names := make(map[string]bool, len(attachments))
+	for _, attachment := range attachments {
+		name := attachment.Name
+		if name == "" || name == "." || name == ".." ||
+			filepath.Base(name) != name {
+			return nil, invalidInvoiceInputError(
+				fmt.Sprintf("invalid attachment name %q", name))
+		}
+		if name == invoiceFilenameCSV || name == invoiceFilenameJSON {
+			return nil, invalidInvoiceInputError(
+				fmt.Sprintf("attachment name %v is reserved", name))
+		}
+		if names[name] {
+			return nil, invalidInvoiceInputError(
+				fmt.Sprintf("duplicate attachment name %v", name))
+		}
+		names[name] = true
+
+		supported := false
+		for _, mime := range v1.PolicyInvoiceAttachmentMIMETypes {
+			if attachment.MIME == mime {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return nil, v1.UserError{
+				ErrorCode:    v1.ErrorStatusUnsupportedMIMEType,
+				ErrorContext: []string{name},
+			}
+		}
+
+		data, err := verifyFileDigest(&attachment)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(data) > v1.PolicyMaxInvoiceAttachmentSize {
+			return nil, invalidInvoiceInputError(fmt.Sprintf(
+				"attachment %v exceeds the maximum size of %v bytes", name,
+				v1.PolicyMaxInvoiceAttachmentSize))
+		}
+
+		if http.DetectContentType(data) != attachment.MIME {
+			return nil, v1.UserError{
+				ErrorCode:    v1.ErrorStatusInvalidMIMEType,
+				ErrorContext: []string{name},
+			}
+		}
+	}
+
+	return names, nil
+}
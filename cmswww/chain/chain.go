@@ -0,0 +1,81 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"github.com/decred/dcrd/dcrutil"
+)
+
+// Tx is a transaction which sends funds to an address being watched.
+type Tx struct {
+	TxID          string // Transaction id
+	Amount        uint64 // Amount sent to the address in atoms
+	Timestamp     int64  // Time the tx was mined, or seen if it's unmined
+	Confirmations uint64 // Number of confirmations of the tx
+}
+
+// Watcher is the interface that all blockchain backends used for detecting
+// invoice payments must implement.
+type Watcher interface {
+	// FetchTxs returns the txs which send funds to the address and were
+	// made no earlier than txNotBefore, including unconfirmed ones.
+	FetchTxs(address string, txNotBefore int64) ([]Tx, error)
+}
+
+// RawTx contains the fields used from a verbose tx, as returned by dcrd's
+// searchrawtransactions command and by dcrdata's address API.
+type RawTx struct {
+	TxID string `json:"txid"`
+	Vout []struct {
+		Value        float64 `json:"value"`
+		ScriptPubKey struct {
+			Addresses []string `json:"addresses"`
+		} `json:"scriptPubKey"`
+	} `json:"vout"`
+	Confirmations uint64 `json:"confirmations"`
+	Time          int64  `json:"time"`
+	Blocktime     int64  `json:"blocktime"`
+}
+
+// ConvertRawTxs returns the txs which send funds to the address, out of the
+// given raw txs, which were made no earlier than txNotBefore. Unmined txs
+// are timestamped with the given time.
+func ConvertRawTxs(rawTxs []RawTx, address string, txNotBefore, now int64) ([]Tx, error) {
+	txs := make([]Tx, 0, len(rawTxs))
+	for _, rawTx := range rawTxs {
+		tx := Tx{
+			TxID:          rawTx.TxID,
+			Timestamp:     rawTx.Blocktime,
+			Confirmations: rawTx.Confirmations,
+		}
+		if tx.Timestamp == 0 {
+			tx.Timestamp = rawTx.Time
+		}
+		if tx.Timestamp == 0 {
+			tx.Timestamp = now
+		}
+
+		for _, vout := range rawTx.Vout {
+			for _, voutAddress := range vout.ScriptPubKey.Addresses {
+				if voutAddress != address {
+					continue
+				}
+
+				value, err := dcrutil.NewAmount(vout.Value)
+				if err != nil {
+					return nil, err
+				}
+				tx.Amount += uint64(value)
+			}
+		}
+
+		if tx.Amount == 0 || tx.Timestamp < txNotBefore {
+			continue
+		}
+		txs = append(txs, tx)
+	}
+
+	return txs, nil
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package dcrrpc
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/decred/contractor-mgmt/cmswww/chain"
+)
+
+const (
+	// searchPageSize is the number of txs requested at a time when
+	// searching the txs of an address.
+	searchPageSize = 100
+
+	// errCodeNoTxInfo is the error code returned by dcrd when there are
+	// no txs for an address.
+	errCodeNoTxInfo = -5
+)
+
+var (
+	_ chain.Watcher = (*dcrrpc)(nil)
+)
+
+// dcrrpc implements the chain watcher interface using the JSON-RPC API of a
+// dcrd node, which must be running with --addrindex. A dcrwallet RPC server
+// can be used as well, since it passes the calls through to its dcrd node.
+type dcrrpc struct {
+	id     uint64 // Id of the last request; first for 64-bit alignment
+	host   string
+	user   string
+	pass   string
+	client *http.Client
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Code, e.Message)
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// call executes a JSON-RPC command and unmarshals its result.
+func (d *dcrrpc) call(method string, params []interface{}, result interface{}) error {
+	b, err := json.Marshal(rpcRequest{
+		JSONRPC: "1.0",
+		ID:      atomic.AddUint64(&d.id, 1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.host, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(d.user, d.pass)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var rr rpcResponse
+	err = json.Unmarshal(body, &rr)
+	if err != nil {
+		return fmt.Errorf("%v: %v", resp.Status, string(body))
+	}
+	if rr.Error != nil {
+		return rr.Error
+	}
+
+	return json.Unmarshal(rr.Result, result)
+}
+
+// FetchTxs satisfies the chain watcher interface.
+func (d *dcrrpc) FetchTxs(address string, txNotBefore int64) ([]chain.Tx, error) {
+	var txs []chain.Tx
+	for skip := 0; ; skip += searchPageSize {
+		var rawTxs []chain.RawTx
+		err := d.call("searchrawtransactions", []interface{}{address, 1,
+			skip, searchPageSize, 0, false}, &rawTxs)
+		if err != nil {
+			if e, ok := err.(*rpcError); ok && e.Code == errCodeNoTxInfo {
+				return txs, nil
+			}
+			return nil, err
+		}
+
+		pageTxs, err := chain.ConvertRawTxs(rawTxs, address, txNotBefore,
+			time.Now().Unix())
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, pageTxs...)
+
+		if len(rawTxs) < searchPageSize {
+			return txs, nil
+		}
+	}
+}
+
+// New returns a chain watcher which uses the dcrd or dcrwallet RPC server at
+// the given host. If a certificate is provided, it's used to verify the
+// server instead of the system certificate pool.
+func New(host, user, pass string, cert []byte) (chain.Watcher, error) {
+	tlsConfig := &tls.Config{}
+	if len(cert) > 0 {
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(cert) {
+			return nil, fmt.Errorf("invalid dcrd RPC certificate")
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	return &dcrrpc{
+		host: host,
+		user: user,
+		pass: pass,
+		client: &http.Client{
+			Timeout: time.Minute,
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}, nil
+}
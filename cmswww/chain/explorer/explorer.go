@@ -0,0 +1,88 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package explorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/wire"
+
+	"github.com/decred/contractor-mgmt/cmswww/chain"
+)
+
+const (
+	dcrdataMainnet = "https://explorer.dcrdata.org/api"
+	dcrdataTestnet = "https://testnet.dcrdata.org/api"
+
+	// maxTxs is the maximum number of txs fetched for an address.
+	maxTxs = 1000
+)
+
+var (
+	_ chain.Watcher = (*explorer)(nil)
+)
+
+// explorer implements the chain watcher interface using the public dcrdata
+// block explorer.
+type explorer struct {
+	url    string
+	client *http.Client
+}
+
+// FetchTxs satisfies the chain watcher interface.
+func (e *explorer) FetchTxs(address string, txNotBefore int64) ([]chain.Tx, error) {
+	url := fmt.Sprintf("%v/address/%v/count/%v/raw", e.url, address, maxTxs)
+	resp, err := e.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("dcrdata error: %v %v", resp.Status,
+			string(body))
+	}
+
+	// dcrdata replies with null when the address has no txs.
+	var rawTxs []chain.RawTx
+	err = json.Unmarshal(body, &rawTxs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dcrdata reply: %v", err)
+	}
+
+	return chain.ConvertRawTxs(rawTxs, address, txNotBefore,
+		time.Now().Unix())
+}
+
+// New returns a chain watcher which uses the public block explorer for the
+// given network.
+func New(params *chaincfg.Params) (chain.Watcher, error) {
+	var url string
+	switch params.Net {
+	case wire.MainNet:
+		url = dcrdataMainnet
+	case wire.TestNet3:
+		url = dcrdataTestnet
+	default:
+		return nil, fmt.Errorf("there is no public block explorer for %v",
+			params.Name)
+	}
+
+	return &explorer{
+		url: url,
+		client: &http.Client{
+			Timeout: time.Minute,
+		},
+	}, nil
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package memory
+
+import (
+	"sync"
+
+	"github.com/decred/contractor-mgmt/cmswww/chain"
+)
+
+var (
+	_ chain.Watcher = (*Memory)(nil)
+)
+
+// Memory implements the chain watcher interface with txs which are added by
+// the caller, so that payment detection can be exercised without a network.
+type Memory struct {
+	sync.RWMutex
+	txs map[string][]chain.Tx // [address][]tx
+}
+
+// AddTx adds a tx which sends funds to the given address.
+func (m *Memory) AddTx(address string, tx chain.Tx) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.txs[address] = append(m.txs[address], tx)
+}
+
+// SetConfirmations updates the number of confirmations of a tx, which
+// simulates blocks being mined on top of it.
+func (m *Memory) SetConfirmations(txID string, confirmations uint64) {
+	m.Lock()
+	defer m.Unlock()
+
+	for address := range m.txs {
+		for idx := range m.txs[address] {
+			if m.txs[address][idx].TxID == txID {
+				m.txs[address][idx].Confirmations = confirmations
+			}
+		}
+	}
+}
+
+// FetchTxs satisfies the chain watcher interface.
+func (m *Memory) FetchTxs(address string, txNotBefore int64) ([]chain.Tx, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	// Return copies so that the caller can't modify the stored txs.
+	var txs []chain.Tx
+	for _, tx := range m.txs[address] {
+		if tx.Timestamp >= txNotBefore {
+			txs = append(txs, tx)
+		}
+	}
+	return txs, nil
+}
+
+// New returns an empty in-memory chain watcher.
+func New() *Memory {
+	return &Memory{
+		txs: make(map[string][]chain.Tx),
+	}
+}
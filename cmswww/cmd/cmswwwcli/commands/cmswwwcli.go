@@ -15,7 +15,7 @@ type Options struct {
 	Verbose    func()             `short:"v" long:"verbose" description:"Print request and response details"`
 
 	// cli commands
-	Login                   LoginCmd                   `command:"login" description:"Login to the contractor mgmt system.\n\n           Parameters: <email> <password>\n  --------------------------------------"`
+	Login                   LoginCmd                   `command:"login" description:"Login to the contractor mgmt system.\n\n           Parameters: <email> [password] [ --totp <code> ]\n   Without a password, the saved identity of the user signs a challenge from the server instead.\n   The code is required once two-factor authentication is enabled.\n  --------------------------------------"`
 	Logout                  LogoutCmd                  `command:"logout" description:"Logout of the contractor mgmt system. Parameters: none\n  --------------------------------------"`
 	NewIdentity             NewIdentityCmd             `command:"newidentity" description:"Generate a new identity. Parameters: none\n  --------------------------------------"`
 	VerifyNewIdentity       VerifyIdentityCmd          `command:"verifyidentity" description:"Verify a newly generated identity.\n\n           Parameters: <token>\n  --------------------------------------"`
@@ -24,20 +24,33 @@ type Options struct {
 	Version                 VersionCmd                 `command:"version" description:"Fetch server info and CSRF token. Parameters: none\n  --------------------------------------"`
 	InviteNewUser           InviteNewUserCmd           `command:"invite" description:"Send a new contractor invitation.\n\n           Parameters: <email>\n  --------------------------------------"`
 	UserDetails             UserDetailsCmd             `command:"user" description:"Fetch a user's details given the user id.\n\n           Parameters: <user id/email/username>\n  --------------------------------------"`
-	ManageUser              ManageUserCmd              `command:"manageuser" description:"Manage a user by user id.\n\n           Parameters: <user id/email/username> <action> <reason>\n    Available actions: resendinvite, resendidentitytoken, lock, unlock\n  --------------------------------------"`
+	ManageUser              ManageUserCmd              `command:"manageuser" description:"Manage a user by user id.\n\n           Parameters: <user id/email/username> <action> <reason>\n    Available actions: resendinvite, resendidentitytoken, lock, unlock, resettotp\n  --------------------------------------"`
+	SetHourlyRate           SetHourlyRateCmd           `command:"setrate" description:"Sets a contractor's hourly rate (in USD), optionally for a single type of work.\n\n           Parameters: <user id/email/username> <rate> [ --worktype <type> ]\n   A rate of 0 for a type of work removes it.\n  --------------------------------------"`
+	SetRole                 SetRoleCmd                 `command:"setrole" description:"Grants a role to a user, or revokes it, which gives them part of the privileges of an admin.\n\n           Parameters: <user id/email/username> <role> [ --revoke ]\n   Available roles: reviewer, treasurer, usermanager, auditor\n  --------------------------------------"`
 	EditUser                EditUserCmd                `command:"edituser" description:"Edit a user's details.\n\n           Parameters: [ --name <name> ] [ --location <location> ]\n  --------------------------------------"`
 	UpdateExtendedPublicKey UpdateExtendedPublicKeyCmd `command:"updatexpublickey" description:"Edit a user's extended public key.\n\n           Parameters: [ --token <verification token> ] [ --xpubkey <xpubkey> ]\n  --------------------------------------"`
 	ChangePassword          ChangePasswordCmd          `command:"changepassword" description:"Change your password.\n\n           Parameters: <current password> <new password>\n  --------------------------------------"`
 	ResetPassword           ResetPasswordCmd           `command:"resetpassword" description:"Reset your password.\n\n           Parameters: <email> <new password>\n  --------------------------------------"`
-	SubmitInvoice           SubmitInvoiceCmd           `command:"submitinvoice" description:"Submits an invoice for a given month and year.\n\n           Parameters: <month> <year>\n  --------------------------------------"`
-	InvoiceDetails          InvoiceDetailsCmd          `command:"invoice" description:"Displays an invoice's details.\n\n           Parameters: <token>\n  --------------------------------------"`
-	Invoices                InvoicesCmd                `command:"invoices" description:"Lists invoices with a particular status for a given month and year.\n\n           Parameters: <month> <year> [ --status <status> ]\n   Available statuses: unreviewed, rejected, approved, paid\n  --------------------------------------"`
-	MyInvoices              MyInvoicesCmd              `command:"myinvoices" description:"Lists a user's invoices with a particular status.\n\n           Parameters: [status]\n   Available statuses: unreviewed, rejected, approved, paid\n  --------------------------------------"`
-	SetInvoiceStatus        SetInvoiceStatusCmd        `command:"setinvoicestatus" description:"Changes an invoice's status.\n\n           Parameters: <token> <status>\n   Available statuses: rejected, approved, paid\n  --------------------------------------"`
+	EnrollTOTP              EnrollTOTPCmd              `command:"enrolltotp" description:"Generates a secret for two-factor authentication, to be added to an authenticator app. Parameters: none\n   Run verifytotp with a code from the app to enable it.\n  --------------------------------------"`
+	VerifyTOTP              VerifyTOTPCmd              `command:"verifytotp" description:"Enables two-factor authentication.\n\n           Parameters: <code>\n  --------------------------------------"`
+	DisableTOTP             DisableTOTPCmd             `command:"disabletotp" description:"Disables two-factor authentication.\n\n           Parameters: <code>\n  --------------------------------------"`
+	SubmitInvoice           SubmitInvoiceCmd           `command:"submitinvoice" description:"Submits an invoice for a given month and year, or a CSV or JSON invoice file.\n\n           Parameters: <month> <year> | --invoice <filepath> [ --attachment <filepath> ... ]\n   Attachments are PNG or PDF receipts, referenced by filename from expense line items.\n  --------------------------------------"`
+	EditInvoice             EditInvoiceCmd             `command:"editinvoice" description:"Submits a revision of a rejected invoice for a given month and year.\n\n           Parameters: <month> <year> [ --token <token> ] [ --attachment <filepath> ... ]\n  --------------------------------------"`
+	InvoiceDetails          InvoiceDetailsCmd          `command:"invoice" description:"Displays an invoice's details.\n\n           Parameters: <token> [ --version <version> ]\n  --------------------------------------"`
+	Invoices                InvoicesCmd                `command:"invoices" description:"Lists invoices with a particular status for a given month and year.\n\n           Parameters: <month> <year> [ --status <status> ]\n   Available statuses: unreviewed, rejected, approved, partiallypaid, paid\n  --------------------------------------"`
+	MyInvoices              MyInvoicesCmd              `command:"myinvoices" description:"Lists a user's invoices with a particular status.\n\n           Parameters: [status]\n   Available statuses: unreviewed, rejected, approved, partiallypaid, paid\n  --------------------------------------"`
+	SetInvoiceStatus        SetInvoiceStatusCmd        `command:"setinvoicestatus" description:"Changes an invoice's status.\n\n           Parameters: <token> <status> [reason]\n   Available statuses: rejected, approved, paid\n   A reason is required when rejecting an invoice.\n  --------------------------------------"`
+	NewComment              NewCommentCmd              `command:"newcomment" description:"Comments on an invoice, optionally in reply to another comment.\n\n           Parameters: <token> <comment> [ --parent <comment id> ]\n  --------------------------------------"`
+	Comments                CommentsCmd                `command:"comments" description:"Displays the comments on an invoice.\n\n           Parameters: <token>\n  --------------------------------------"`
 	LogWork                 LogWorkCmd                 `command:"logwork" description:"Adds a line item to an invoice.\n\n           Parameters: <month> <year>\n  --------------------------------------"`
-	DCRUSD                  DCRUSDCmd                  `command:"dcrusd" description:"Calculates the DCR-USD for a given month & year.\n\n           Parameters: <month> <year>\n  --------------------------------------"`
+	DCRUSD                  DCRUSDCmd                  `command:"dcrusd" description:"Displays the DCR rate of a given month & year in a currency (USD by default), as computed by the server from its rate sources.\n\n           Parameters: <month> <year> [ --currency <currency> ] [ --fetch ]\n   Use --fetch to compute the rate again, which is only possible until it's locked.\n  --------------------------------------"`
+	LockRate                LockRateCmd                `command:"lockrate" description:"Locks the DCR rate of a given month & year in a currency (USD by default), after which it's used to pay the invoices of the month billed in that currency.\n\n           Parameters: <month> <year> [ --currency <currency> ]\n  --------------------------------------"`
 	ReviewInvoices          ReviewInvoicesCmd          `command:"reviewinvoices" description:"Generates a list of submitted invoices that are ready for initial review.\n\n           Parameters: <month> <year>\n  --------------------------------------"`
-	PayInvoices             PayInvoicesCmd             `command:"payinvoices" description:"Generates a list of unpaid invoices that are ready for payment.\n\n           Parameters: <month> <year> <DCR-USD rate>\n  --------------------------------------"`
+	ReviewLineItem          ReviewLineItemCmd          `command:"reviewlineitem" description:"Approves or disputes a single line item of an invoice.\n\n           Parameters: <token> <line number> <status> [note]\n   Available statuses: approved, disputed\n   A note is required when disputing a line item.\n  --------------------------------------"`
+	ProposalSpending        ProposalSpendingCmd        `command:"proposalspending" description:"Displays the approved and paid spend per proposal across all invoices, along with its budget.\n\n           Parameters: [proposal token]\n  --------------------------------------"`
+	SetProposalBudget       SetProposalBudgetCmd       `command:"setproposalbudget" description:"Sets the maximum amount (in USD) that can be billed against a proposal; 0 removes the budget.\n\n           Parameters: <proposal token> <budget>\n  --------------------------------------"`
+	PayInvoices             PayInvoicesCmd             `command:"payinvoices" description:"Generates a list of unpaid invoices that are ready for payment, excluding disputed line items. Outstanding quotes are reported as they are unless --requote is given. Use --out to write a payout batch signed by the server.\n\n           Parameters: <month> <year> [DCR-USD rate]\n   The locked rate of the month is used if no rate is given.\n  --------------------------------------"`
+	ReconcilePayment        ReconcilePaymentCmd        `command:"reconcilepayment" description:"Marks a partially paid invoice as paid, once the rest of the payment has been settled.\n\n           Parameters: <token> <reason>\n  --------------------------------------"`
 }
 
 var Ctx *client.Ctx
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
+)
+
+type CommentsCmd struct {
+	Args struct {
+		Token string `positional-arg-name:"token"`
+	} `positional-args:"true" required:"true"`
+}
+
+func printComments(comments []v1.InvoiceComment, parentID uint64, depth int) {
+	indent := strings.Repeat("    ", depth)
+	for _, comment := range comments {
+		if comment.ParentID != parentID {
+			continue
+		}
+
+		fmt.Printf("%v  %v • %v • %v\n", indent, comment.CommentID,
+			comment.Username, time.Unix(comment.Timestamp, 0))
+		for _, line := range strings.Split(comment.Comment, "\n") {
+			fmt.Printf("%v    %v\n", indent, line)
+		}
+
+		printComments(comments, comment.CommentID, depth+1)
+	}
+}
+
+func (cmd *CommentsCmd) Execute(args []string) error {
+	err := InitialVersionRequest()
+	if err != nil {
+		return err
+	}
+
+	if config.LoggedInUser == nil {
+		return ErrNotLoggedIn
+	}
+
+	ic := v1.InvoiceComments{
+		Token: cmd.Args.Token,
+	}
+
+	var icr v1.InvoiceCommentsReply
+	err = Ctx.Get(v1.RouteInvoiceComments, ic, &icr)
+	if err != nil {
+		return err
+	}
+
+	if !config.JSONOutput {
+		fmt.Printf("Comments: ")
+		if len(icr.Comments) == 0 {
+			fmt.Printf("none\n")
+		} else {
+			fmt.Println()
+			printComments(icr.Comments, 0, 0)
+		}
+	}
+
+	return nil
+}
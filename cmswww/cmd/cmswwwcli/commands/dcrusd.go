@@ -1,16 +1,10 @@
 package commands
 
 import (
-	"crypto/tls"
-	"encoding/json"
 	"fmt"
-	"math"
-	"net/http"
-	"sort"
 	"time"
 
-	"github.com/decred/politeia/util"
-
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
 	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
 )
 
@@ -19,106 +13,92 @@ type DCRUSDCmd struct {
 		Month string `positional-arg-name:"month"`
 		Year  uint16 `positional-arg-name:"year"`
 	} `positional-args:"true" required:"true"`
-
-	httpClient   *http.Client
-	Month        uint16
-	Year         uint16
-	StartOfMonth time.Time
-	EndOfMonth   time.Time
-}
-
-type DCRUSDSource struct {
-	Name string
-	Func DCRUSDValueSourceFunc
+	Currency string `long:"currency" optional:"true" description:"Currency of the rate (default: USD)"`
+	Fetch    bool   `long:"fetch" optional:"true" description:"Fetch the rate from the rate sources again, unless it's locked"`
 }
 
-type DCRUSDValueSourceFunc func() (float64, error)
-
-func (cmd *DCRUSDCmd) makeRequest(url string, resp interface{}) error {
-	req, err := cmd.httpClient.Get(url)
-	if err != nil {
-		return err
-	}
-	defer req.Body.Close()
-
-	responseBody := util.ConvertBodyToByteArray(req.Body, false)
-
-	if req.StatusCode != http.StatusOK {
-		return fmt.Errorf("%v: %v", req.Status, string(responseBody))
+// formatAmount formats an amount with its currency; an empty currency is
+// USD.
+func formatAmount(amount interface{}, currency string) string {
+	if currency == "" || currency == v1.PolicyInvoiceCurrency {
+		return fmt.Sprintf("$%v", amount)
 	}
-
-	return json.Unmarshal(responseBody, resp)
+	return fmt.Sprintf("%v %v", amount, currency)
 }
 
-func (cmd *DCRUSDCmd) getMedianValue(values sort.Float64Slice) float64 {
-	values.Sort()
-
-	l := len(values)
+// formatCents formats an amount in cents of a currency.
+func formatCents(cents uint64, currency string) string {
+	return formatAmount(fmt.Sprintf("%v.%02d", cents/100, cents%100),
+		currency)
+}
 
-	// Invalid input.
-	if l == 0 {
-		return math.NaN()
+func printMonthlyRate(rate v1.MonthlyRateRecord) {
+	date := time.Date(int(rate.Year), time.Month(rate.Month), 1, 0, 0, 0, 0,
+		time.UTC)
+
+	fmt.Printf("             Month: %v\n", date.Format("January 2006"))
+	fmt.Printf("      DCR-%v rate: %v\n", rate.Currency,
+		formatCents(rate.DCRRateCents, rate.Currency))
+	fmt.Printf("        Fetched at: %v\n", time.Unix(rate.Timestamp, 0))
+	for _, source := range rate.Sources {
+		if source.Error != "" {
+			fmt.Printf("    %14v: error: %v\n", source.Name, source.Error)
+			continue
+		}
+		fmt.Printf("    %14v: %v\n", source.Name,
+			formatCents(source.DCRRateCents, rate.Currency))
 	}
-
-	// Odd number of values, return the middle one.
-	if l%2 == 1 {
-		return values[l/2]
+	if rate.Locked {
+		fmt.Printf("         Locked at: %v\n", time.Unix(rate.LockedAt, 0))
+		fmt.Printf("         Locked by: %v\n", rate.LockedBy)
+	} else {
+		fmt.Printf("   (not locked; use lockrate to agree on it)\n")
 	}
-
-	// Even number of values, average the middle two.
-	return (values[(l/2)-1] + values[l/2]) / 2
 }
 
 func (cmd *DCRUSDCmd) Execute(args []string) error {
-	var err error
-	cmd.Month, err = ParseMonth(cmd.Args.Month)
+	err := InitialVersionRequest()
 	if err != nil {
 		return err
 	}
-	cmd.Year = cmd.Args.Year
 
-	cmd.StartOfMonth = time.Date(int(cmd.Year), time.Month(cmd.Month), 1, 0, 0, 0, 0, time.UTC)
-	cmd.EndOfMonth = cmd.StartOfMonth.AddDate(0, 1, 0).Add(-1 * time.Nanosecond)
-
-	if config.Verbose {
-		fmt.Printf("Start of month: %v\n", cmd.StartOfMonth.Unix())
-		fmt.Printf("End of month: %v\n", cmd.EndOfMonth.Unix())
-		fmt.Println()
+	month, err := ParseMonth(cmd.Args.Month)
+	if err != nil {
+		return err
 	}
 
-	cmd.httpClient = &http.Client{
-		Transport: &http.Transport{
-			IdleConnTimeout: 60 * time.Second,
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: false,
-			},
-		},
-	}
+	var rate v1.MonthlyRateRecord
+	if cmd.Fetch {
+		fmr := v1.FetchMonthlyRate{
+			Month:    month,
+			Year:     cmd.Args.Year,
+			Currency: cmd.Currency,
+		}
 
-	var values []float64
-	sources := []DCRUSDSource{
-		{
-			Name: "Bittrex",
-			Func: cmd.getBittrexValue,
-		},
-		{
-			Name: "Coinmetrics",
-			Func: cmd.getCoinmetricsValue,
-		},
-	}
+		var fmrr v1.FetchMonthlyRateReply
+		err = Ctx.Post(v1.RouteFetchMonthlyRate, fmr, &fmrr)
+		if err != nil {
+			return err
+		}
+		rate = fmrr.Rate
+	} else {
+		mr := v1.MonthlyRate{
+			Month:    month,
+			Year:     cmd.Args.Year,
+			Currency: cmd.Currency,
+		}
 
-	for _, source := range sources {
-		value, err := source.Func()
+		var mrr v1.MonthlyRateReply
+		err = Ctx.Get(v1.RouteMonthlyRate, mr, &mrr)
 		if err != nil {
-			fmt.Printf("Error while fetching %v value: %v\n", source.Name, err)
-		} else {
-			values = append(values, value)
-			fmt.Printf("%v: $%.2f\n", source.Name, value)
+			return err
 		}
-		fmt.Println()
+		rate = mrr.Rate
+	}
+
+	if !config.JSONOutput {
+		printMonthlyRate(rate)
 	}
 
-	medianVal := cmd.getMedianValue(values)
-	fmt.Printf("Median: $%.2f\n", medianVal)
 	return nil
 }
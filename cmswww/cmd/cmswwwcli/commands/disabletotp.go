@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+)
+
+type DisableTOTPCmd struct {
+	Args struct {
+		Code string `positional-arg-name:"code"`
+	} `positional-args:"true" required:"true"`
+}
+
+func (cmd *DisableTOTPCmd) Execute(args []string) error {
+	err := InitialVersionRequest()
+	if err != nil {
+		return err
+	}
+
+	dt := v1.DisableTOTP{
+		Code: cmd.Args.Code,
+	}
+
+	var dtr v1.DisableTOTPReply
+	return Ctx.Post(v1.RouteDisableTOTP, dt, &dtr)
+}
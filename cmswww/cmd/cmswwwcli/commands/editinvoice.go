@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
+)
+
+type EditInvoiceCmd struct {
+	Args struct {
+		Month string `positional-arg-name:"month"`
+		Year  uint16 `positional-arg-name:"year"`
+	} `positional-args:"true" optional:"true"`
+	InvoiceFilename string   `long:"invoice" optional:"true" description:"Filepath to an invoice CSV"`
+	Attachments     []string `long:"attachment" optional:"true" description:"Filepath to a PNG or PDF receipt for an expense; may be repeated, and replaces the existing receipts"`
+	Token           string   `long:"token" optional:"true" description:"Token of the invoice to revise; defaults to the token in the submission record for the month"`
+}
+
+func loadSubmissionRecord(month, year uint16) (*SubmissionRecord, error) {
+	filename, err := config.GetInvoiceSubmissionRecordFilename(month, year)
+	if err != nil {
+		return nil, err
+	}
+
+	if !config.FileExists(filename) {
+		return nil, fmt.Errorf("No submission record found for %v, please "+
+			"provide the invoice token", config.GetInvoiceMonthStr(month, year))
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var submissionRecord SubmissionRecord
+	err = json.Unmarshal(data, &submissionRecord)
+	return &submissionRecord, err
+}
+
+func (cmd *EditInvoiceCmd) Execute(args []string) error {
+	err := InitialVersionRequest()
+	if err != nil {
+		return err
+	}
+
+	id := config.LoggedInUserIdentity
+	if id == nil {
+		return ErrNotLoggedIn
+	}
+
+	filename, err := resolveInvoiceFilename(cmd.Args.Month, cmd.Args.Year,
+		cmd.InvoiceFilename)
+	if err != nil {
+		return err
+	}
+
+	file, attachments, merkleRoot, signature, err := loadInvoiceFile(filename,
+		cmd.Attachments, id)
+	if err != nil {
+		return err
+	}
+
+	token := cmd.Token
+	if token == "" {
+		submissionRecord, err := loadSubmissionRecord(month, year)
+		if err != nil {
+			return err
+		}
+		token = submissionRecord.CensorshipRecord.Token
+	}
+
+	ei := v1.EditInvoice{
+		Token:       token,
+		File:        *file,
+		Attachments: attachments,
+		PublicKey:   hex.EncodeToString(id.Public.Key[:]),
+		Signature:   signature,
+	}
+
+	var eir v1.EditInvoiceReply
+	err = Ctx.Post(v1.RouteEditInvoice, ei, &eir)
+	if err != nil {
+		return err
+	}
+
+	if eir.Invoice.CensorshipRecord.Merkle != merkleRoot {
+		return fmt.Errorf("Digest returned from server did not match client's"+
+			" digest: %v %v", merkleRoot, eir.Invoice.CensorshipRecord.Merkle)
+	}
+
+	// Replace the submission record with the one for the new version.
+	filename, err = saveSubmissionRecord(&SubmissionRecord{
+		ServerPublicKey: config.ServerPublicKey,
+		Submission: v1.SubmitInvoice{
+			Month:       month,
+			Year:        year,
+			File:        *file,
+			Attachments: attachments,
+			PublicKey:   ei.PublicKey,
+			Signature:   ei.Signature,
+		},
+		CensorshipRecord: eir.Invoice.CensorshipRecord,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !config.JSONOutput {
+		fmt.Printf("Invoice revised successfully, it is now at version %v! "+
+			"The censorship record has been stored in %v for your future "+
+			"reference.", eir.Invoice.Version, filename)
+	}
+
+	return nil
+}
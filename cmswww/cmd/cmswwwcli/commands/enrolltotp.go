@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
+)
+
+type EnrollTOTPCmd struct{}
+
+func (cmd *EnrollTOTPCmd) Execute(args []string) error {
+	err := InitialVersionRequest()
+	if err != nil {
+		return err
+	}
+
+	var etr v1.EnrollTOTPReply
+	err = Ctx.Post(v1.RouteEnrollTOTP, v1.EnrollTOTP{}, &etr)
+	if err != nil {
+		return err
+	}
+
+	if !config.JSONOutput {
+		fmt.Printf("Secret: %v\n", etr.Secret)
+		fmt.Printf("URI:    %v\n", etr.URI)
+		fmt.Printf("\nAdd the secret to your authenticator app, then run " +
+			"verifytotp with a code from the app to enable two-factor " +
+			"authentication.\n")
+	}
+	return nil
+}
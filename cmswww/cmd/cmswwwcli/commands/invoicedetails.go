@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/decred/dcrd/dcrutil"
+
 	"github.com/decred/contractor-mgmt/cmswww/api/v1"
 	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
 )
@@ -12,6 +14,7 @@ type InvoiceDetailsCmd struct {
 	Args struct {
 		Token string `positional-arg-name:"token"`
 	} `positional-args:"true" optional:"true"`
+	Version uint64 `long:"version" optional:"true" description:"Invoice version; defaults to the latest version"`
 }
 
 func (cmd *InvoiceDetailsCmd) Execute(args []string) error {
@@ -21,7 +24,8 @@ func (cmd *InvoiceDetailsCmd) Execute(args []string) error {
 	}
 
 	id := v1.InvoiceDetails{
-		Token: cmd.Args.Token,
+		Token:   cmd.Args.Token,
+		Version: cmd.Version,
 	}
 
 	var idr v1.InvoiceDetailsReply
@@ -38,6 +42,55 @@ func (cmd *InvoiceDetailsCmd) Execute(args []string) error {
 		fmt.Printf("    Submitted by: %v\n", idr.Invoice.Username)
 		fmt.Printf("              at: %v\n", time.Unix(idr.Invoice.Timestamp, 0))
 		fmt.Printf("             For: %v\n", date.Format("January 2006"))
+		fmt.Printf("         Version: %v\n", idr.Invoice.Version)
+		fmt.Printf("       Approvals: %v of %v\n",
+			len(idr.Invoice.Approvers), idr.Invoice.ApprovalsRequired)
+		for _, approver := range idr.Invoice.Approvers {
+			fmt.Printf("                  %v\n", approver)
+		}
+		for idx, attachment := range idr.Invoice.Attachments {
+			label := ""
+			if idx == 0 {
+				label = "Attachments:"
+			}
+			fmt.Printf("    %12v %v (%v)\n", label, attachment.Name,
+				attachment.MIME)
+		}
+
+		if len(idr.Invoice.Payments) > 0 {
+			fmt.Printf("        Payments:\n")
+			for _, payment := range idr.Invoice.Payments {
+				fmt.Printf("           %v to %v\n",
+					dcrutil.Amount(payment.Amount), payment.Address)
+				if len(payment.TxIDs) == 0 {
+					fmt.Printf("             Not detected yet\n")
+					continue
+				}
+				for _, txID := range payment.TxIDs {
+					fmt.Printf("             Tx: %v\n", txID)
+				}
+				fmt.Printf("             Received: %v\n",
+					dcrutil.Amount(payment.AmountReceived))
+				fmt.Printf("             Detected at: %v (%v confirmations)\n",
+					time.Unix(payment.DetectedAt, 0), payment.Confirmations)
+			}
+		}
+
+		if len(idr.Invoice.Changes) > 0 {
+			fmt.Printf("         History:\n")
+			for _, change := range idr.Invoice.Changes {
+				status := v1.InvoiceStatus[change.NewStatus]
+				if change.Approval &&
+					change.NewStatus != v1.InvoiceStatusApproved {
+					status = "approval recorded"
+				}
+				fmt.Printf("           %v • %v\n",
+					time.Unix(change.Timestamp, 0), status)
+				if change.Reason != "" {
+					fmt.Printf("             Reason: %v\n", change.Reason)
+				}
+			}
+		}
 	}
 
 	return nil
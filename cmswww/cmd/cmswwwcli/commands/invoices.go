@@ -19,10 +19,11 @@ type InvoicesCmd struct {
 
 var (
 	invoiceStatuses = map[string]v1.InvoiceStatusT{
-		"unreviewed": v1.InvoiceStatusNotReviewed,
-		"rejected":   v1.InvoiceStatusRejected,
-		"approved":   v1.InvoiceStatusApproved,
-		"paid":       v1.InvoiceStatusPaid,
+		"unreviewed":    v1.InvoiceStatusNotReviewed,
+		"rejected":      v1.InvoiceStatusRejected,
+		"approved":      v1.InvoiceStatusApproved,
+		"paid":          v1.InvoiceStatusPaid,
+		"partiallypaid": v1.InvoiceStatusPartiallyPaid,
 	}
 )
 
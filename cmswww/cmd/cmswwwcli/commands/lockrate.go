@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
+)
+
+type LockRateCmd struct {
+	Args struct {
+		Month string `positional-arg-name:"month"`
+		Year  uint16 `positional-arg-name:"year"`
+	} `positional-args:"true" required:"true"`
+	Currency string `long:"currency" optional:"true" description:"Currency of the rate (default: USD)"`
+}
+
+func (cmd *LockRateCmd) Execute(args []string) error {
+	err := InitialVersionRequest()
+	if err != nil {
+		return err
+	}
+
+	id := config.LoggedInUserIdentity
+	if id == nil {
+		return ErrNotLoggedIn
+	}
+
+	month, err := ParseMonth(cmd.Args.Month)
+	if err != nil {
+		return err
+	}
+
+	// Fetch the stored rate so that the rate which is locked is the one
+	// which is signed.
+	var mrr v1.MonthlyRateReply
+	err = Ctx.Get(v1.RouteMonthlyRate, v1.MonthlyRate{
+		Month:    month,
+		Year:     cmd.Args.Year,
+		Currency: cmd.Currency,
+	}, &mrr)
+	if err != nil {
+		return err
+	}
+
+	msg := strconv.FormatUint(uint64(month), 10) +
+		strconv.FormatUint(uint64(cmd.Args.Year), 10) + mrr.Rate.Currency +
+		strconv.FormatUint(mrr.Rate.DCRRateCents, 10)
+	signature := id.SignMessage([]byte(msg))
+
+	lmr := v1.LockMonthlyRate{
+		Month:        month,
+		Year:         cmd.Args.Year,
+		Currency:     mrr.Rate.Currency,
+		DCRRateCents: mrr.Rate.DCRRateCents,
+		PublicKey:    hex.EncodeToString(id.Public.Key[:]),
+		Signature:    hex.EncodeToString(signature[:]),
+	}
+
+	var lmrr v1.LockMonthlyRateReply
+	err = Ctx.Post(v1.RouteLockMonthlyRate, lmr, &lmrr)
+	if err != nil {
+		return err
+	}
+
+	if !config.JSONOutput {
+		printMonthlyRate(lmrr.Rate)
+	}
+
+	return nil
+}
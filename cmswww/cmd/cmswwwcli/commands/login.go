@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"encoding/hex"
 	"fmt"
 
 	"github.com/decred/contractor-mgmt/cmswww/api/v1"
@@ -11,7 +12,8 @@ type LoginCmd struct {
 	Args struct {
 		Email    string `positional-arg-name:"email"`
 		Password string `positional-arg-name:"password"`
-	} `positional-args:"true" required:"true"`
+	} `positional-args:"true" required:"1"`
+	TOTPCode string `long:"totp" optional:"true" description:"Two-factor authentication code, if enabled"`
 }
 
 func (cmd *LoginCmd) Execute(args []string) error {
@@ -20,13 +22,17 @@ func (cmd *LoginCmd) Execute(args []string) error {
 		return err
 	}
 
-	l := v1.Login{
-		Email:    cmd.Args.Email,
-		Password: cmd.Args.Password,
-	}
-
 	var lr v1.LoginReply
-	err = Ctx.Post(v1.RouteLogin, l, &lr)
+	if cmd.Args.Password == "" {
+		err = cmd.loginWithIdentity(&lr)
+	} else {
+		l := v1.Login{
+			Email:    cmd.Args.Email,
+			Password: cmd.Args.Password,
+			TOTPCode: cmd.TOTPCode,
+		}
+		err = Ctx.Post(v1.RouteLogin, l, &lr)
+	}
 	if err != nil {
 		return err
 	}
@@ -50,3 +56,30 @@ func (cmd *LoginCmd) Execute(args []string) error {
 	}
 	return config.SaveCookies(ck)
 }
+
+// loginWithIdentity logs in by signing a challenge from the server with the
+// saved identity of the user, instead of sending a password.
+func (cmd *LoginCmd) loginWithIdentity(lr *v1.LoginReply) error {
+	id, err := config.LoadUserIdentity(cmd.Args.Email)
+	if err != nil {
+		return fmt.Errorf("Your identity could not be loaded, please log in " +
+			"with your password")
+	}
+
+	var lcr v1.LoginChallengeReply
+	err = Ctx.Post(v1.RouteLoginChallenge, v1.LoginChallenge{
+		Email: cmd.Args.Email,
+	}, &lcr)
+	if err != nil {
+		return err
+	}
+
+	signature := id.SignMessage([]byte(lcr.Challenge))
+	ls := v1.LoginSignature{
+		Email:     cmd.Args.Email,
+		PublicKey: hex.EncodeToString(id.Public.Key[:]),
+		Signature: hex.EncodeToString(signature[:]),
+		TOTPCode:  cmd.TOTPCode,
+	}
+	return Ctx.Post(v1.RouteLoginSignature, ls, lr)
+}
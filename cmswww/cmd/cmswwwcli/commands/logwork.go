@@ -72,13 +72,15 @@ func promptForFieldValues() ([]string, error) {
 			continue
 		}
 
-		if field.Type == v1.InvoiceFieldTypeUint {
-			value, err := strconv.ParseUint(valueStr, 10, 64)
-			if err != nil || value == 0 {
+		// Zero is allowed since expenses, fixed and milestone line items
+		// don't need hours; the server verifies the line item as a whole.
+		if field.Type == v1.InvoiceFieldTypeUint && len(valueStr) > 0 {
+			_, err := strconv.ParseUint(valueStr, 10, 64)
+			if err != nil {
 				if config.JSONOutput {
-					return nil, fmt.Errorf("This field must be a positive number")
+					return nil, fmt.Errorf("This field must be a whole number")
 				}
-				fmt.Println("This field must be a positive number")
+				fmt.Println("This field must be a whole number")
 				continue
 			}
 		}
@@ -19,6 +19,7 @@ var (
 		"resendidentitytoken": v1.UserManageRegenerateUpdateIdentityVerification,
 		"lock":                v1.UserManageLock,
 		"unlock":              v1.UserManageUnlock,
+		"resettotp":           v1.UserManageResetTOTP,
 	}
 )
 
@@ -29,6 +29,7 @@ type invoice struct {
 	Month     uint16  `json:"month"`
 	Year      uint16  `json:"year"`
 	Status    string  `json:"status"`
+	Reason    string  `json:"reason,omitempty"`
 }
 
 type sortableInvoices []invoice
@@ -81,11 +82,19 @@ func fetchSubmittedInvoices(statusStr string) ([]invoice, error) {
 		token := v.CensorshipRecord.Token
 		timestamp := v.Timestamp
 
+		// Include the reason for the latest rejection, so the contractor
+		// knows what to fix before resubmitting.
+		var reason string
+		if v.Status == v1.InvoiceStatusRejected && len(v.Changes) > 0 {
+			reason = v.Changes[len(v.Changes)-1].Reason
+		}
+
 		invoices = append(invoices, invoice{
 			Token:     &token,
 			Month:     v.Month,
 			Year:      v.Year,
 			Status:    v1.InvoiceStatus[v.Status],
+			Reason:    reason,
 			Timestamp: &timestamp,
 		})
 	}
@@ -188,6 +197,9 @@ func (cmd *MyInvoicesCmd) Execute(args []string) error {
 			}
 
 			fmt.Printf("       Status: %v\n", v.Status)
+			if v.Reason != "" {
+				fmt.Printf("       Reason: %v\n", v.Reason)
+			}
 		}
 	}
 
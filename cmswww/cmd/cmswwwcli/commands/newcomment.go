@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
+)
+
+type NewCommentCmd struct {
+	Args struct {
+		Token   string `positional-arg-name:"token"`
+		Comment string `positional-arg-name:"comment"`
+	} `positional-args:"true" required:"true"`
+	ParentID uint64 `long:"parent" optional:"true" description:"ID of the comment to reply to"`
+}
+
+func (cmd *NewCommentCmd) Execute(args []string) error {
+	err := InitialVersionRequest()
+	if err != nil {
+		return err
+	}
+
+	id := config.LoggedInUserIdentity
+	if id == nil {
+		return ErrNotLoggedIn
+	}
+
+	msg := cmd.Args.Token + strconv.FormatUint(cmd.ParentID, 10) +
+		cmd.Args.Comment
+	signature := id.SignMessage([]byte(msg))
+
+	nc := v1.NewInvoiceComment{
+		Token:     cmd.Args.Token,
+		ParentID:  cmd.ParentID,
+		Comment:   cmd.Args.Comment,
+		PublicKey: hex.EncodeToString(id.Public.Key[:]),
+		Signature: hex.EncodeToString(signature[:]),
+	}
+
+	var ncr v1.NewInvoiceCommentReply
+	err = Ctx.Post(v1.RouteNewInvoiceComment, nc, &ncr)
+	if err != nil {
+		return err
+	}
+
+	if !config.JSONOutput {
+		fmt.Printf("Comment submitted with id %v\n", ncr.Comment.CommentID)
+	}
+
+	return nil
+}
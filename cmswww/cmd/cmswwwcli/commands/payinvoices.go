@@ -2,6 +2,11 @@ package commands
 
 import (
 	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/politeia/util"
 
 	"github.com/decred/contractor-mgmt/cmswww/api/v1"
 	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
@@ -9,10 +14,30 @@ import (
 
 type PayInvoicesCmd struct {
 	Args struct {
-		Month      string  `positional-arg-name:"month"`
-		Year       uint16  `positional-arg-name:"year"`
-		DCRUSDRate float64 `positional-arg-name:"dcrusdrate"`
-	} `positional-args:"true" required:"true"`
+		Month      string `positional-arg-name:"month" required:"true"`
+		Year       uint16 `positional-arg-name:"year" required:"true"`
+		DCRUSDRate string `positional-arg-name:"dcrusdrate"`
+	} `positional-args:"true"`
+	Requote bool   `long:"requote" optional:"true" description:"Replace outstanding quotes with new ones at the current rate"`
+	Out     string `long:"out" optional:"true" description:"Filepath to write the signed payout batch to; the signature is written to the same path with a .sig extension"`
+	Format  string `long:"format" optional:"true" description:"Format of the payout batch: json (default) or csv"`
+}
+
+// verifyPayoutBatch verifies the server signature of a payout batch.
+func verifyPayoutBatch(batch *v1.SignedPayoutBatch) error {
+	id, err := util.IdentityFromString(batch.PublicKey)
+	if err != nil {
+		return err
+	}
+	sig, err := util.ConvertSignature(batch.Signature)
+	if err != nil {
+		return err
+	}
+	if !id.VerifyMessage([]byte(batch.Payload), sig) {
+		return fmt.Errorf("could not verify payout batch signature")
+	}
+
+	return nil
 }
 
 func (cmd *PayInvoicesCmd) Execute(args []string) error {
@@ -26,10 +51,26 @@ func (cmd *PayInvoicesCmd) Execute(args []string) error {
 		return err
 	}
 
+	// The server uses the locked rate of the month if no rate is given.
+	var dcrUSDRateCents uint64
+	if cmd.Args.DCRUSDRate != "" {
+		dcrUSDRateCents, err = ParseUSDCents(cmd.Args.DCRUSDRate)
+		if err != nil {
+			return err
+		}
+	}
+
 	pi := v1.PayInvoices{
-		Month:      month,
-		Year:       cmd.Args.Year,
-		DCRUSDRate: cmd.Args.DCRUSDRate,
+		Month:           month,
+		Year:            cmd.Args.Year,
+		DCRUSDRateCents: dcrUSDRateCents,
+		Requote:         cmd.Requote,
+	}
+	if cmd.Out != "" {
+		pi.BatchFormat = v1.PayoutBatchFormatJSON
+		if cmd.Format != "" {
+			pi.BatchFormat = cmd.Format
+		}
 	}
 
 	var pir v1.PayInvoicesReply
@@ -38,6 +79,23 @@ func (cmd *PayInvoicesCmd) Execute(args []string) error {
 		return err
 	}
 
+	if pir.Batch != nil {
+		err = verifyPayoutBatch(pir.Batch)
+		if err != nil {
+			return err
+		}
+
+		err = ioutil.WriteFile(cmd.Out, []byte(pir.Batch.Payload), 0600)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(cmd.Out+".sig",
+			[]byte(pir.Batch.Signature+"\n"), 0600)
+		if err != nil {
+			return err
+		}
+	}
+
 	if !config.JSONOutput {
 		fmt.Printf("Invoices ready to be paid: ")
 		if len(pir.Invoices) == 0 {
@@ -47,19 +105,59 @@ func (cmd *PayInvoicesCmd) Execute(args []string) error {
 				fmt.Println()
 				fmt.Println()
 
-				rate := float64(invoice.TotalCostUSD) / float64(invoice.TotalHours)
+				rate := float64(invoice.TotalCost) / float64(invoice.TotalHours)
 
 				fmt.Printf("           User ID: %v\n", invoice.UserID)
 				fmt.Printf("          Username: %v\n", invoice.Username)
 				fmt.Printf("   ------------------------------------------\n")
 				fmt.Printf("             Hours: %v\n", invoice.TotalHours)
-				fmt.Printf("        Total cost: $%v\n", invoice.TotalCostUSD)
-				fmt.Printf("      Average Rate: $%.2f / hr\n", rate)
+				fmt.Printf("        Total cost: %v\n",
+					formatAmount(invoice.TotalCost, invoice.Currency))
+				if invoice.Currency != v1.PolicyInvoiceCurrency {
+					fmt.Printf("                    (%v)\n",
+						formatAmount(invoice.TotalCostUSD, ""))
+				}
+				fmt.Printf("      Average Rate: %v / hr\n",
+					formatAmount(fmt.Sprintf("%.2f", rate), invoice.Currency))
 				fmt.Printf("   ------------------------------------------\n")
-				fmt.Printf("        Total cost: %v DCR\n", invoice.TotalCostDCR)
+				fmt.Printf("        Total cost: %v\n",
+					dcrutil.Amount(invoice.TotalCostAtoms))
+				if invoice.Currency != v1.PolicyInvoiceCurrency {
+					fmt.Printf("      DCR-%v rate: %v\n", invoice.Currency,
+						formatCents(invoice.DCRRateCents, invoice.Currency))
+				}
+				fmt.Printf("      DCR-USD rate: %v\n",
+					formatCents(invoice.DCRUSDRateCents, ""))
 				fmt.Printf("   Payment Address: %v\n", invoice.PaymentAddress)
+				fmt.Printf("         Quoted at: %v\n",
+					time.Unix(invoice.QuotedAt, 0))
+				if invoice.Existing {
+					fmt.Printf("   (outstanding quote; use --requote to " +
+						"replace it)\n")
+				}
 			}
 		}
+
+		if len(pir.Invoices) > 0 {
+			fmt.Println()
+			fmt.Printf("Total: %v (%v)\n", dcrutil.Amount(pir.TotalCostAtoms),
+				formatAmount(pir.TotalCostUSD, ""))
+		}
+
+		if len(pir.ApprovedByPayer) > 0 {
+			fmt.Println()
+			fmt.Printf("Skipped invoices you approved, which have to be " +
+				"paid by another admin:\n")
+			for _, token := range pir.ApprovedByPayer {
+				fmt.Printf("  %v\n", token)
+			}
+		}
+
+		if pir.Batch != nil {
+			fmt.Println()
+			fmt.Printf("Payout batch written to %v\n", cmd.Out)
+			fmt.Printf("  Signed by server key %v\n", pir.Batch.PublicKey)
+		}
 	}
 
 	return nil
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
+)
+
+type ProposalSpendingCmd struct {
+	Args struct {
+		Proposal string `positional-arg-name:"proposal"`
+	} `positional-args:"true" optional:"true"`
+}
+
+func printProposalSpend(ps v1.ProposalSpend) {
+	fmt.Printf("          Proposal: %v\n", ps.Proposal)
+	if ps.BudgetUSD > 0 {
+		fmt.Printf("            Budget: $%v\n", ps.BudgetUSD)
+	} else {
+		fmt.Printf("            Budget: none\n")
+	}
+	fmt.Printf("          Approved: $%v\n", ps.ApprovedUSD)
+	fmt.Printf("              Paid: $%v\n", ps.PaidUSD)
+	if ps.OverBudget {
+		fmt.Printf("   The proposal is over budget\n")
+	}
+	if ps.SpendUnknown {
+		fmt.Printf("   Some spend is left out, since its month has no " +
+			"rate\n")
+	}
+}
+
+func (cmd *ProposalSpendingCmd) Execute(args []string) error {
+	err := InitialVersionRequest()
+	if err != nil {
+		return err
+	}
+
+	ps := v1.ProposalSpending{
+		Proposal: cmd.Args.Proposal,
+	}
+
+	var psr v1.ProposalSpendingReply
+	err = Ctx.Get(v1.RouteProposalSpending, ps, &psr)
+	if err != nil {
+		return err
+	}
+
+	if !config.JSONOutput {
+		fmt.Printf("Proposal spending: ")
+		if len(psr.Proposals) == 0 {
+			fmt.Printf("none\n")
+		} else {
+			fmt.Println()
+			for idx, proposalSpend := range psr.Proposals {
+				if idx > 0 {
+					fmt.Printf("   ------------------------------------------\n")
+				}
+				printProposalSpend(proposalSpend)
+			}
+		}
+	}
+
+	return nil
+}
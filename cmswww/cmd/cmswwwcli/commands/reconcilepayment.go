@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
+)
+
+type ReconcilePaymentCmd struct {
+	Args struct {
+		Token  string `positional-arg-name:"token"`
+		Reason string `positional-arg-name:"reason"`
+	} `positional-args:"true" required:"true"`
+}
+
+func (cmd *ReconcilePaymentCmd) Execute(args []string) error {
+	err := InitialVersionRequest()
+	if err != nil {
+		return err
+	}
+
+	id := config.LoggedInUserIdentity
+	if id == nil {
+		return ErrNotLoggedIn
+	}
+
+	signature := id.SignMessage([]byte(cmd.Args.Token + cmd.Args.Reason))
+
+	rip := v1.ReconcileInvoicePayment{
+		Token:     cmd.Args.Token,
+		Reason:    cmd.Args.Reason,
+		PublicKey: hex.EncodeToString(id.Public.Key[:]),
+		Signature: hex.EncodeToString(signature[:]),
+	}
+
+	var ripr v1.ReconcileInvoicePaymentReply
+	err = Ctx.Post(v1.RouteReconcileInvoicePayment, rip, &ripr)
+	if err != nil {
+		return err
+	}
+
+	if !config.JSONOutput {
+		fmt.Printf("Status changed to %v\n",
+			v1.InvoiceStatus[ripr.Invoice.Status])
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/decred/contractor-mgmt/cmswww/api/v1"
 	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
@@ -14,6 +15,33 @@ type ReviewInvoicesCmd struct {
 	} `positional-args:"true" required:"true"`
 }
 
+// printProposalTotals prints the spend per proposal, which the server
+// normalizes to USD.
+func printProposalTotals(proposalTotals []v1.InvoiceProposalTotal) {
+	for _, proposalTotal := range proposalTotals {
+		fmt.Printf("   ------------------------------------------\n")
+		fmt.Printf("          Proposal: %v\n", proposalTotal.Proposal)
+		fmt.Printf("             Hours: %v\n", proposalTotal.TotalHours)
+		fmt.Printf("        Total cost: $%v\n", proposalTotal.TotalCostUSD)
+		if len(proposalTotal.Milestones) > 0 {
+			fmt.Printf("        Milestones: %v\n",
+				strings.Join(proposalTotal.Milestones, ", "))
+		}
+		if proposalTotal.BudgetUSD > 0 {
+			fmt.Printf("            Budget: $%v ($%v already spent)\n",
+				proposalTotal.BudgetUSD, proposalTotal.SpentUSD)
+		}
+		if proposalTotal.OverBudget {
+			fmt.Printf("   WARNING: approving this would put the proposal " +
+				"over budget\n")
+		}
+		if proposalTotal.SpendUnknown {
+			fmt.Printf("   WARNING: some of the spend is left out, since " +
+				"its month has no rate\n")
+		}
+	}
+}
+
 func (cmd *ReviewInvoicesCmd) Execute(args []string) error {
 	err := InitialVersionRequest()
 	if err != nil {
@@ -45,8 +73,6 @@ func (cmd *ReviewInvoicesCmd) Execute(args []string) error {
 				fmt.Println()
 				fmt.Println()
 
-				totalRate := float64(invoice.TotalCostUSD) / float64(invoice.TotalHours)
-
 				fmt.Printf("           User ID: %v\n", invoice.UserID)
 				fmt.Printf("          Username: %v\n", invoice.Username)
 				fmt.Printf("             Token: %v\n", invoice.Token)
@@ -56,7 +82,11 @@ func (cmd *ReviewInvoicesCmd) Execute(args []string) error {
 						fmt.Printf("        --------------------------------\n")
 					}
 
-					rate := float64(lineItem.TotalCost) / float64(lineItem.Hours)
+					fmt.Printf("            Line item: %v\n", lineItem.LineNumber)
+					if lineItem.Kind != v1.LineItemKindHourly {
+						fmt.Printf("                 Kind: %v\n",
+							v1.LineItemKind[lineItem.Kind])
+					}
 					fmt.Printf("                 Type: %v\n", lineItem.Type)
 					if lineItem.Subtype != "" {
 						fmt.Printf("              Subtype: %v\n", lineItem.Subtype)
@@ -65,14 +95,76 @@ func (cmd *ReviewInvoicesCmd) Execute(args []string) error {
 					if lineItem.Proposal != "" {
 						fmt.Printf("    Politeia proposal: %v\n", lineItem.Proposal)
 					}
+					if lineItem.Milestone != "" {
+						fmt.Printf("            Milestone: %v\n", lineItem.Milestone)
+					}
 					fmt.Printf("                Hours: %v\n", lineItem.Hours)
-					fmt.Printf("           Total cost: $%v\n", lineItem.TotalCost)
-					fmt.Printf("                 Rate: $%.2f / hr\n", rate)
+					fmt.Printf("           Total cost: %v\n",
+						formatAmount(lineItem.TotalCost, invoice.Currency))
+					if lineItem.Receipt != "" {
+						fmt.Printf("              Receipt: %v\n", lineItem.Receipt)
+					}
+					if lineItem.Rate > 0 {
+						fmt.Printf("                 Rate: %v / hr\n",
+							formatAmount(lineItem.Rate, invoice.Currency))
+						fmt.Printf("        Expected cost: %v\n",
+							formatAmount(lineItem.ExpectedCost, invoice.Currency))
+						if lineItem.CostMismatch {
+							fmt.Printf("                       " +
+								"(total cost does not match the hourly rate)\n")
+						}
+					} else if lineItem.Hours > 0 {
+						rate := float64(lineItem.TotalCost) / float64(lineItem.Hours)
+						fmt.Printf("                 Rate: %v / hr\n",
+							formatAmount(fmt.Sprintf("%.2f", rate), invoice.Currency))
+					}
+					fmt.Printf("               Status: %v\n",
+						v1.LineItemStatus[lineItem.Status])
+					if lineItem.Note != "" {
+						fmt.Printf("                 Note: %v\n", lineItem.Note)
+					}
 				}
 				fmt.Printf("   ------------------------------------------\n")
 				fmt.Printf("             Hours: %v\n", invoice.TotalHours)
-				fmt.Printf("        Total cost: $%v\n", invoice.TotalCostUSD)
-				fmt.Printf("      Average Rate: $%.2f / hr\n", totalRate)
+				fmt.Printf("        Total cost: %v\n",
+					formatAmount(invoice.TotalCost, invoice.Currency))
+				if invoice.Currency != v1.PolicyInvoiceCurrency {
+					if invoice.TotalCostUSD > 0 {
+						fmt.Printf("                    (%v)\n",
+							formatAmount(invoice.TotalCostUSD, ""))
+					} else {
+						fmt.Printf("                    (no rate to convert " +
+							"it to USD yet)\n")
+					}
+				}
+				if invoice.TotalHours > 0 {
+					totalRate := float64(invoice.TotalCost) / float64(invoice.TotalHours)
+					fmt.Printf("      Average Rate: %v / hr\n",
+						formatAmount(fmt.Sprintf("%.2f", totalRate),
+							invoice.Currency))
+				}
+				if invoice.CostMismatch {
+					fmt.Printf("   Some line items do not match the contractor's " +
+						"hourly rate\n")
+				}
+				printProposalTotals(invoice.Proposals)
+			}
+
+			fmt.Println()
+			fmt.Println()
+			fmt.Printf("Total cost of the invoices: %v\n",
+				formatAmount(rir.TotalCostUSD, ""))
+			if len(rir.MissingRates) > 0 {
+				fmt.Printf("  Leaving out the invoices which can't be "+
+					"converted to USD for lack of a %v rate\n",
+					strings.Join(rir.MissingRates, ", "))
+			}
+
+			if len(rir.Proposals) > 0 {
+				fmt.Println()
+				fmt.Println()
+				fmt.Printf("Spend per proposal for the month:\n")
+				printProposalTotals(rir.Proposals)
 			}
 		}
 	}
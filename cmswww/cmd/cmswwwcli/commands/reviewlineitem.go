@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
+)
+
+type ReviewLineItemCmd struct {
+	Args struct {
+		Token      string `positional-arg-name:"token"`
+		LineNumber uint64 `positional-arg-name:"linenumber"`
+		Status     string `positional-arg-name:"status"`
+		Note       string `positional-arg-name:"note"`
+	} `positional-args:"true" optional:"true"`
+}
+
+var (
+	lineItemStatuses = map[string]v1.LineItemStatusT{
+		"approved": v1.LineItemStatusApproved,
+		"disputed": v1.LineItemStatusDisputed,
+	}
+)
+
+func (cmd *ReviewLineItemCmd) Execute(args []string) error {
+	err := InitialVersionRequest()
+	if err != nil {
+		return err
+	}
+
+	id := config.LoggedInUserIdentity
+	if id == nil {
+		return ErrNotLoggedIn
+	}
+
+	status, ok := lineItemStatuses[strings.ToLower(cmd.Args.Status)]
+	if !ok {
+		return fmt.Errorf("Invalid status: %v", cmd.Args.Status)
+	}
+
+	msg := cmd.Args.Token + strconv.FormatUint(cmd.Args.LineNumber, 10) +
+		strconv.FormatUint(uint64(status), 10) + cmd.Args.Note
+	signature := id.SignMessage([]byte(msg))
+
+	rli := v1.ReviewInvoiceLineItem{
+		Token:      cmd.Args.Token,
+		LineNumber: cmd.Args.LineNumber,
+		Status:     status,
+		Note:       cmd.Args.Note,
+		PublicKey:  hex.EncodeToString(id.Public.Key[:]),
+		Signature:  hex.EncodeToString(signature[:]),
+	}
+
+	var rlir v1.ReviewInvoiceLineItemReply
+	err = Ctx.Post(v1.RouteReviewInvoiceLineItem, rli, &rlir)
+	if err != nil {
+		return err
+	}
+
+	if !config.JSONOutput {
+		fmt.Printf("Line item %v status changed to %v\n", cmd.Args.LineNumber,
+			v1.LineItemStatus[status])
+	}
+
+	return nil
+}
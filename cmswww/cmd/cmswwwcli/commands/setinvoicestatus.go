@@ -14,6 +14,7 @@ type SetInvoiceStatusCmd struct {
 	Args struct {
 		Token  string `positional-arg-name:"token"`
 		Status string `positional-arg-name:"status"`
+		Reason string `positional-arg-name:"reason"`
 	} `positional-args:"true" optional:"true"`
 }
 
@@ -33,12 +34,14 @@ func (cmd *SetInvoiceStatusCmd) Execute(args []string) error {
 		return fmt.Errorf("Invalid status: %v", cmd.Args.Status)
 	}
 
-	msg := cmd.Args.Token + strconv.FormatUint(uint64(status), 10)
+	msg := cmd.Args.Token + strconv.FormatUint(uint64(status), 10) +
+		cmd.Args.Reason
 	signature := id.SignMessage([]byte(msg))
 
 	sis := v1.SetInvoiceStatus{
 		Token:     cmd.Args.Token,
 		Status:    status,
+		Reason:    cmd.Args.Reason,
 		PublicKey: hex.EncodeToString(id.Public.Key[:]),
 		Signature: hex.EncodeToString(signature[:]),
 	}
@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
+)
+
+type SetProposalBudgetCmd struct {
+	Args struct {
+		Proposal string `positional-arg-name:"proposal"`
+		Budget   uint64 `positional-arg-name:"budget"`
+	} `positional-args:"true" required:"true"`
+}
+
+func (cmd *SetProposalBudgetCmd) Execute(args []string) error {
+	err := InitialVersionRequest()
+	if err != nil {
+		return err
+	}
+
+	spb := v1.SetProposalBudget{
+		Proposal:  cmd.Args.Proposal,
+		BudgetUSD: cmd.Args.Budget,
+	}
+
+	var spbr v1.SetProposalBudgetReply
+	err = Ctx.Post(v1.RouteSetProposalBudget, spb, &spbr)
+	if err != nil {
+		return err
+	}
+
+	if !config.JSONOutput {
+		printProposalSpend(spbr.Proposal)
+	}
+
+	return nil
+}
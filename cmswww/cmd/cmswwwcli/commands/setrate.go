@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
+)
+
+type SetHourlyRateCmd struct {
+	Args struct {
+		User string `positional-arg-name:"user"`
+		Rate uint64 `positional-arg-name:"rate"`
+	} `positional-args:"true" required:"true"`
+	WorkType string `long:"worktype" optional:"true" description:"The type of work the rate applies to"`
+}
+
+func (cmd *SetHourlyRateCmd) Execute(args []string) error {
+	err := InitialVersionRequest()
+	if err != nil {
+		return err
+	}
+
+	sr := v1.SetUserHourlyRate{
+		UserID:     cmd.Args.User,
+		Email:      cmd.Args.User,
+		Username:   cmd.Args.User,
+		WorkType:   cmd.WorkType,
+		HourlyRate: cmd.Args.Rate,
+	}
+
+	var srr v1.SetUserHourlyRateReply
+	err = Ctx.Post(v1.RouteSetUserHourlyRate, sr, &srr)
+	if err != nil {
+		return err
+	}
+
+	if !config.JSONOutput {
+		fmt.Printf("Hourly rate set for %v\n", srr.User.Username)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
+)
+
+type SetRoleCmd struct {
+	Args struct {
+		User string `positional-arg-name:"user"`
+		Role string `positional-arg-name:"role"`
+	} `positional-args:"true" required:"true"`
+	Revoke bool `long:"revoke" optional:"true" description:"Revoke the role instead of granting it"`
+}
+
+func (cmd *SetRoleCmd) Execute(args []string) error {
+	err := InitialVersionRequest()
+	if err != nil {
+		return err
+	}
+
+	role := v1.UserRoleInvalid
+	for r, name := range v1.UserRole {
+		if name == cmd.Args.Role {
+			role = r
+		}
+	}
+	if role == v1.UserRoleInvalid {
+		return fmt.Errorf("%v is an invalid role", cmd.Args.Role)
+	}
+
+	sr := v1.SetUserRole{
+		UserID:   cmd.Args.User,
+		Email:    cmd.Args.User,
+		Username: cmd.Args.User,
+		Role:     role,
+		Revoke:   cmd.Revoke,
+	}
+
+	var srr v1.SetUserRoleReply
+	err = Ctx.Post(v1.RouteSetUserRole, sr, &srr)
+	if err != nil {
+		return err
+	}
+
+	if !config.JSONOutput {
+		if cmd.Revoke {
+			fmt.Printf("Role %v revoked from %v\n", cmd.Args.Role,
+				srr.User.Username)
+		} else {
+			fmt.Printf("Role %v granted to %v\n", cmd.Args.Role,
+				srr.User.Username)
+		}
+	}
+
+	return nil
+}
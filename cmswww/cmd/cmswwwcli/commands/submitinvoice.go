@@ -9,10 +9,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/decred/dcrtime/merkle"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+
 	"github.com/decred/contractor-mgmt/cmswww/api/v1"
 	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
 )
@@ -22,7 +28,8 @@ type SubmitInvoiceCmd struct {
 		Month string `positional-arg-name:"month"`
 		Year  uint16 `positional-arg-name:"year"`
 	} `positional-args:"true" optional:"true"`
-	InvoiceFilename string `long:"invoice" optional:"true" description:"Filepath to an invoice CSV"`
+	InvoiceFilename string   `long:"invoice" optional:"true" description:"Filepath to an invoice CSV or JSON file"`
+	Attachments     []string `long:"attachment" optional:"true" description:"Filepath to a PNG or PDF receipt for an expense; may be repeated"`
 }
 
 // SubmissionRecord is a record of an invoice submission to the server,
@@ -33,6 +40,35 @@ type SubmissionRecord struct {
 	CensorshipRecord v1.CensorshipRecord `json:"censorshiprecord"`
 }
 
+// isJSONInvoiceFile returns whether the invoice file is in the JSON format
+// rather than the CSV format.
+func isJSONInvoiceFile(filename string) bool {
+	return strings.ToLower(filepath.Ext(filename)) == ".json"
+}
+
+// validateJSONInvoiceFile verifies that the invoice file can be decoded
+// and sets the month and year from its content.
+func validateJSONInvoiceFile(filename string) error {
+	payload, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var ii v1.InvoiceInput
+	err = json.Unmarshal(payload, &ii)
+	if err != nil {
+		return fmt.Errorf("Unable to decode JSON invoice %v: %v", filename, err)
+	}
+
+	if ii.Month < 1 || ii.Month > 12 || ii.Year == 0 {
+		return fmt.Errorf("JSON invoice must contain a valid month and year")
+	}
+
+	year = ii.Year
+	month = ii.Month
+	return nil
+}
+
 func validateInvoiceFile(filename string) error {
 	// Verify invoice file exists.
 	if !config.FileExists(filename) {
@@ -41,6 +77,10 @@ func validateInvoiceFile(filename string) error {
 			filename)
 	}
 
+	if isJSONInvoiceFile(filename) {
+		return validateJSONInvoiceFile(filename)
+	}
+
 	// Verify the invoice file is formatted correctly according to policy.
 	policy, err := fetchPolicy()
 	if err != nil {
@@ -57,6 +97,7 @@ func validateInvoiceFile(filename string) error {
 	csvReader.Comma = policy.Invoice.FieldDelimiterChar
 	csvReader.Comment = policy.Invoice.CommentChar
 	csvReader.TrimLeadingSpace = true
+	csvReader.FieldsPerRecord = -1
 
 	_, err = csvReader.ReadAll()
 	if err != nil {
@@ -88,61 +129,163 @@ func validateInvoiceFile(filename string) error {
 	return nil
 }
 
-func (cmd *SubmitInvoiceCmd) Execute(args []string) error {
-	err := InitialVersionRequest()
+// resolveInvoiceFilename returns the filepath of the invoice given either
+// a month and year or an explicit filepath.
+func resolveInvoiceFilename(monthStr string, yearArg uint16, invoiceFilename string) (string, error) {
+	if monthStr != "" && yearArg != 0 {
+		month, err := ParseMonth(monthStr)
+		if err != nil {
+			return "", err
+		}
+		year = yearArg
+
+		return config.GetInvoiceFilename(month, year)
+	}
+
+	if invoiceFilename != "" {
+		return invoiceFilename, nil
+	}
+
+	return "", fmt.Errorf("You must supply either a month and year or the " +
+		"filepath to an invoice in proper CSV or JSON format.")
+}
+
+// loadFile reads a file and returns it with the given MIME type; if the MIME
+// type is empty, it's detected from the file content.
+func loadFile(filename, mime string) (*v1.File, error) {
+	payload, err := ioutil.ReadFile(filename)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	id := config.LoggedInUserIdentity
-	if id == nil {
-		return ErrNotLoggedIn
+	if mime == "" {
+		mime = http.DetectContentType(payload)
 	}
 
-	var filename string
-	if cmd.Args.Month != "" && cmd.Args.Year != 0 {
-		month, err := ParseMonth(cmd.Args.Month)
+	h := sha256.New()
+	h.Write(payload)
+	return &v1.File{
+		Name:    filepath.Base(filename),
+		MIME:    mime,
+		Digest:  hex.EncodeToString(h.Sum(nil)),
+		Payload: base64.StdEncoding.EncodeToString(payload),
+	}, nil
+}
+
+// invoiceMerkleRoot returns the merkle root of the digests of the invoice
+// file followed by its attachments sorted by name, which is what the server
+// expects to be signed.
+func invoiceMerkleRoot(file *v1.File, attachments []v1.File) (string, error) {
+	files := append([]v1.File{*file}, attachments...)
+	hashes := make([]*[sha256.Size]byte, 0, len(files))
+	for _, f := range files {
+		b, err := hex.DecodeString(f.Digest)
 		if err != nil {
-			return err
+			return "", err
 		}
-		year = cmd.Args.Year
 
-		filename, err = config.GetInvoiceFilename(month, year)
+		var d [sha256.Size]byte
+		copy(d[:], b)
+		hashes = append(hashes, &d)
+	}
+
+	return hex.EncodeToString(merkle.Root(hashes)[:]), nil
+}
+
+// loadInvoiceFile validates and reads the invoice file and its attachments
+// and returns them along with their merkle root and its signature.
+func loadInvoiceFile(filename string, attachmentFilenames []string, id *identity.FullIdentity) (*v1.File, []v1.File, string, string, error) {
+	err := validateInvoiceFile(filename)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+
+	mime := v1.MIMETypeInvoiceCSV
+	if isJSONInvoiceFile(filename) {
+		mime = v1.MIMETypeInvoiceJSON
+	}
+
+	file, err := loadFile(filename, mime)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+
+	attachments := make([]v1.File, 0, len(attachmentFilenames))
+	for _, attachmentFilename := range attachmentFilenames {
+		attachment, err := loadFile(attachmentFilename, "")
 		if err != nil {
-			return err
+			return nil, nil, "", "", err
 		}
-	} else if cmd.InvoiceFilename != "" {
-		filename = cmd.InvoiceFilename
-	} else {
-		return fmt.Errorf("You must supply either a month and year or the " +
-			"filepath to an invoice in proper CSV format.")
+		attachments = append(attachments, *attachment)
+	}
+	sort.Slice(attachments, func(i, j int) bool {
+		return attachments[i].Name < attachments[j].Name
+	})
+
+	merkleRoot, err := invoiceMerkleRoot(file, attachments)
+	if err != nil {
+		return nil, nil, "", "", err
 	}
+	signature := id.SignMessage([]byte(merkleRoot))
+
+	return file, attachments, merkleRoot, hex.EncodeToString(signature[:]), nil
+}
 
-	err = validateInvoiceFile(filename)
+// saveSubmissionRecord stores the submission record in case the submitter
+// ever needs it, replacing any previous record for the same month.
+func saveSubmissionRecord(submissionRecord *SubmissionRecord) (string, error) {
+	data, err := json.MarshalIndent(submissionRecord, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	filename, err := config.GetInvoiceSubmissionRecordFilename(
+		submissionRecord.Submission.Month, submissionRecord.Submission.Year)
+	if err != nil {
+		return "", err
+	}
+
+	// The record is stored as read-only, so remove the existing one first.
+	if config.FileExists(filename) {
+		err = os.Remove(filename)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return filename, ioutil.WriteFile(filename, data, 0400)
+}
+
+func (cmd *SubmitInvoiceCmd) Execute(args []string) error {
+	err := InitialVersionRequest()
 	if err != nil {
 		return err
 	}
 
-	payload, err := ioutil.ReadFile(filename)
+	id := config.LoggedInUserIdentity
+	if id == nil {
+		return ErrNotLoggedIn
+	}
+
+	filename, err := resolveInvoiceFilename(cmd.Args.Month, cmd.Args.Year,
+		cmd.InvoiceFilename)
 	if err != nil {
 		return err
 	}
 
-	h := sha256.New()
-	h.Write(payload)
-	digest := hex.EncodeToString(h.Sum(nil))
-	signature := id.SignMessage([]byte(digest))
+	file, attachments, merkleRoot, signature, err := loadInvoiceFile(filename,
+		cmd.Attachments, id)
+	if err != nil {
+		return err
+	}
 
 	ni := v1.SubmitInvoice{
-		Month: month,
-		Year:  year,
-		File: v1.File{
-			MIME:    "text/plain; charset=utf-8",
-			Digest:  digest,
-			Payload: base64.StdEncoding.EncodeToString(payload),
-		},
-		PublicKey: hex.EncodeToString(id.Public.Key[:]),
-		Signature: hex.EncodeToString(signature[:]),
+		Month:       month,
+		Year:        year,
+		File:        *file,
+		Attachments: attachments,
+		PublicKey:   hex.EncodeToString(id.Public.Key[:]),
+		Signature:   signature,
 	}
 
 	var nir v1.SubmitInvoiceReply
@@ -151,28 +294,17 @@ func (cmd *SubmitInvoiceCmd) Execute(args []string) error {
 		return err
 	}
 
-	if nir.CensorshipRecord.Merkle != digest {
+	if nir.CensorshipRecord.Merkle != merkleRoot {
 		return fmt.Errorf("Digest returned from server did not match client's"+
-			" digest: %v %v", digest, nir.CensorshipRecord.Merkle)
+			" digest: %v %v", merkleRoot, nir.CensorshipRecord.Merkle)
 	}
 
 	// Store the submission record in case the submitter ever needs it.
-	submissionRecord := SubmissionRecord{
+	filename, err = saveSubmissionRecord(&SubmissionRecord{
 		ServerPublicKey:  config.ServerPublicKey,
 		Submission:       ni,
 		CensorshipRecord: nir.CensorshipRecord,
-	}
-	data, err := json.MarshalIndent(submissionRecord, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	filename, err = config.GetInvoiceSubmissionRecordFilename(month, year)
-	if err != nil {
-		return err
-	}
-
-	err = ioutil.WriteFile(filename, data, 0400)
+	})
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,8 @@ package commands
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/decred/contractor-mgmt/cmswww/api/v1"
 	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
@@ -38,11 +40,27 @@ func (cmd *UserDetailsCmd) Execute(args []string) error {
 		fmt.Printf("                  Email: %v\n", udr.User.Email)
 		fmt.Printf("               Username: %v\n", udr.User.Username)
 		fmt.Printf("                  Admin: %v\n", udr.User.Admin)
+		if len(udr.User.Roles) > 0 {
+			roles := make([]string, 0, len(udr.User.Roles))
+			for _, role := range udr.User.Roles {
+				roles = append(roles, v1.UserRole[role])
+			}
+			fmt.Printf("                  Roles: %v\n", strings.Join(roles, ", "))
+		}
 		fmt.Printf("    Extended public key: %v\n", udr.User.ExtendedPublicKey)
 		fmt.Printf("             Last login: %v\n", udr.User.LastLogin)
 		fmt.Printf("  Failed login attempts: %v\n", udr.User.FailedLoginAttempts)
-		fmt.Printf("                 Locked: %v\n",
-			udr.User.FailedLoginAttempts >= v1.LoginAttemptsToLockUser)
+		fmt.Printf("                 Locked: %v\n", udr.User.Locked)
+		if udr.User.Locked && udr.User.LockedUntil != 0 {
+			fmt.Printf("           Locked until: %v\n",
+				time.Unix(udr.User.LockedUntil, 0))
+		}
+		if udr.User.HourlyRate > 0 {
+			fmt.Printf("            Hourly rate: $%v / hr\n", udr.User.HourlyRate)
+		}
+		for workType, rate := range udr.User.WorkTypeHourlyRates {
+			fmt.Printf("    Hourly rate (%v): $%v / hr\n", workType, rate)
+		}
 	}
 
 	return nil
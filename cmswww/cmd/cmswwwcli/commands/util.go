@@ -49,3 +49,29 @@ func ParseMonth(monthStr string) (uint16, error) {
 
 	return 0, fmt.Errorf("invalid month specified")
 }
+
+// ParseUSDCents parses a USD amount with at most two decimal places, such as
+// 18.45, into cents without going through floating point.
+func ParseUSDCents(usdStr string) (uint64, error) {
+	parts := strings.SplitN(strings.TrimPrefix(usdStr, "$"), ".", 2)
+	dollars, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid USD amount: %v", usdStr)
+	}
+
+	var cents uint64
+	if len(parts) == 2 {
+		if len(parts[1]) == 0 || len(parts[1]) > 2 {
+			return 0, fmt.Errorf("invalid USD amount: %v", usdStr)
+		}
+		cents, err = strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid USD amount: %v", usdStr)
+		}
+		if len(parts[1]) == 1 {
+			cents *= 10
+		}
+	}
+
+	return dollars*100 + cents, nil
+}
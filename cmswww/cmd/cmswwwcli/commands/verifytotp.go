@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/cmd/cmswwwcli/config"
+)
+
+type VerifyTOTPCmd struct {
+	Args struct {
+		Code string `positional-arg-name:"code"`
+	} `positional-args:"true" required:"true"`
+}
+
+func (cmd *VerifyTOTPCmd) Execute(args []string) error {
+	err := InitialVersionRequest()
+	if err != nil {
+		return err
+	}
+
+	vt := v1.VerifyTOTP{
+		Code: cmd.Args.Code,
+	}
+
+	var vtr v1.VerifyTOTPReply
+	err = Ctx.Post(v1.RouteVerifyTOTP, vt, &vtr)
+	if err != nil {
+		return err
+	}
+
+	if !config.JSONOutput {
+		fmt.Printf("Two-factor authentication is now enabled; use the " +
+			"--totp option to log in\n")
+	}
+	return nil
+}
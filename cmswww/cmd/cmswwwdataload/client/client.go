@@ -371,7 +371,7 @@ func (c *Client) ApproveInvoice(token string) error {
 	)
 }
 
-func (c *Client) RejectInvoice(token string) error {
+func (c *Client) RejectInvoice(token, reason string) error {
 	fmt.Printf("Rejecting invoice: %v\n", token)
 
 	var sisr v1.SetInvoiceStatusReply
@@ -383,10 +383,11 @@ func (c *Client) RejectInvoice(token string) error {
 		"setinvoicestatus",
 		token,
 		"rejected",
+		reason,
 	)
 }
 
-func (c *Client) PayInvoices(month, year uint16, dcrUSDRate float64) error {
+func (c *Client) PayInvoices(month, year uint16, dcrUSDRateCents uint64) error {
 	fmt.Printf("Paying invoices\n")
 
 	var pir v1.PayInvoicesReply
@@ -398,7 +399,7 @@ func (c *Client) PayInvoices(month, year uint16, dcrUSDRate float64) error {
 		"payinvoices",
 		strconv.FormatUint(uint64(month), 10),
 		strconv.FormatUint(uint64(year), 10),
-		strconv.FormatFloat(dcrUSDRate, 'f', -1, 64),
+		fmt.Sprintf("%v.%02d", dcrUSDRateCents/100, dcrUSDRateCents%100),
 	)
 }
 
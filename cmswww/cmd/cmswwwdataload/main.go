@@ -162,7 +162,7 @@ func rejectInvoice(email, password, token string) error {
 		return err
 	}
 
-	err := c.RejectInvoice(token)
+	err := c.RejectInvoice(token, "Invoice rejected by dataload")
 	if err != nil {
 		return err
 	}
@@ -170,12 +170,12 @@ func rejectInvoice(email, password, token string) error {
 	return c.Logout()
 }
 
-func payApprovedInvoices(email, password string, month, year uint16, dcrUSDRate float64) error {
+func payApprovedInvoices(email, password string, month, year uint16, dcrUSDRateCents uint64) error {
 	if _, err := c.Login(email, password); err != nil {
 		return err
 	}
 
-	err := c.PayInvoices(month, year, dcrUSDRate)
+	err := c.PayInvoices(month, year, dcrUSDRateCents)
 	if err != nil {
 		return err
 	}
@@ -472,7 +472,7 @@ func _main() error {
 		return err
 	}
 
-	err = payApprovedInvoices(cfg.AdminEmail, cfg.AdminPass, 11, 2018, 20.0)
+	err = payApprovedInvoices(cfg.AdminEmail, cfg.AdminPass, 11, 2018, 2000)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/database"
+)
+
+func validateComment(comment string) error {
+	length := utf8.RuneCountInString(strings.TrimSpace(comment))
+	if length == 0 || length > v1.PolicyMaxCommentLength {
+		return v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidCommentLength,
+		}
+	}
+
+	return nil
+}
+
+// getInvoiceForComments returns the invoice for the given token if the user
+// is allowed to see it, which is required both to read and post comments.
+func (c *cmswww) getInvoiceForComments(token string, user *database.User) (*v1.InvoiceRecord, error) {
+	invoice, err := c.getInvoice(token)
+	if err != nil {
+		if err == database.ErrInvoiceNotFound {
+			return nil, v1.UserError{
+				ErrorCode: v1.ErrorStatusInvoiceNotFound,
+			}
+		}
+		return nil, err
+	}
+
+	err = validateUserCanSeeInvoice(invoice, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return invoice, nil
+}
+
+// HandleNewInvoiceComment adds a comment to an invoice, optionally as a reply
+// to an existing comment.
+func (c *cmswww) HandleNewInvoiceComment(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	nc := req.(*v1.NewInvoiceComment)
+
+	err := checkPublicKeyAndSignature(user, nc.PublicKey, nc.Signature,
+		nc.Token, strconv.FormatUint(nc.ParentID, 10), nc.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateComment(nc.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice, err := c.getInvoiceForComments(nc.Token, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the contractor who submitted the invoice and its reviewers can
+	// post comments; other roles which can see it, like auditors, can only
+	// read them.
+	if invoice.UserID != strconv.FormatUint(user.ID, 10) &&
+		!userHasPermission(user, permissionReviewInvoices) {
+		return nil, v1.UserError{
+			ErrorCode:    v1.ErrorStatusMissingUserRole,
+			ErrorContext: []string{v1.UserRole[v1.UserRoleReviewer]},
+		}
+	}
+
+	// The lock is held so that comment ids are assigned sequentially.
+	c.Lock()
+	defer c.Unlock()
+
+	dbComments, err := c.db.GetInvoiceComments(nc.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if nc.ParentID != 0 {
+		found := false
+		for _, dbComment := range dbComments {
+			if dbComment.CommentID == nc.ParentID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, v1.UserError{
+				ErrorCode: v1.ErrorStatusCommentNotFound,
+			}
+		}
+	}
+
+	mdComment := BackendInvoiceComment{
+		Version:   VersionBackendInvoiceComment,
+		CommentID: uint64(len(dbComments)) + 1,
+		ParentID:  nc.ParentID,
+		Comment:   nc.Comment,
+		PublicKey: nc.PublicKey,
+		Signature: nc.Signature,
+		Timestamp: time.Now().Unix(),
+	}
+
+	err = c.appendVettedMetadata(nc.Token, mdStreamComments, mdComment)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the comment to the database.
+	dbComment := convertStreamCommentToDatabaseInvoiceComment(nc.Token,
+		user.ID, mdComment)
+	err = c.db.CreateInvoiceComment(&dbComment)
+	if err != nil {
+		return nil, err
+	}
+	dbComment.Username = user.Username
+
+	// Return the reply.
+	ncr := v1.NewInvoiceCommentReply{
+		Comment: *convertDatabaseInvoiceCommentToInvoiceComment(&dbComment),
+	}
+	return &ncr, nil
+}
+
+// HandleInvoiceComments returns all comments on an invoice.
+func (c *cmswww) HandleInvoiceComments(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	ic := req.(*v1.InvoiceComments)
+
+	_, err := c.getInvoiceForComments(ic.Token, user)
+	if err != nil {
+		return nil, err
+	}
+
+	dbComments, err := c.db.GetInvoiceComments(ic.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	icr := v1.InvoiceCommentsReply{
+		Comments: convertDatabaseInvoiceCommentsToInvoiceComments(dbComments),
+	}
+	return &icr, nil
+}
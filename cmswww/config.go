@@ -16,6 +16,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	flags "github.com/btcsuite/go-flags"
 	"github.com/dajohi/goemail"
@@ -23,6 +24,9 @@ import (
 	"github.com/decred/politeia/politeiad/api/v1/identity"
 	"github.com/decred/politeia/util"
 
+	"github.com/decred/contractor-mgmt/cmswww/rate/binance"
+	"github.com/decred/contractor-mgmt/cmswww/rate/coingecko"
+	"github.com/decred/contractor-mgmt/cmswww/rate/coinmetrics"
 	"github.com/decred/contractor-mgmt/cmswww/sharedconfig"
 )
 
@@ -32,6 +36,7 @@ const (
 	defaultLogFilename      = "cmswww.log"
 	adminLogFilename        = "admin.log"
 	defaultIdentityFilename = "identity.json"
+	serverIdentityFilename  = "server_identity.json"
 
 	defaultMainnetPort = "4443"
 	defaultTestnetPort = "4443"
@@ -39,6 +44,26 @@ const (
 	allowInteractive = "i-know-this-is-a-bad-idea"
 
 	defaultPaymentMinConfirmations = uint64(2)
+	defaultPaymentPollWorkers      = 4
+	defaultPaymentTolerance        = 0.001
+
+	defaultInvoiceApprovals = 1
+
+	defaultLockDuration    = 15 * time.Minute
+	defaultMaxLockDuration = 24 * time.Hour
+
+	// Supported chain watchers, used to detect invoice payments.
+	chainWatcherExplorer = "explorer"
+	chainWatcherDcrRPC   = "dcrrpc"
+	chainWatcherMemory   = "memory"
+
+	// Supported sources of the monthly DCR/USD rate.
+	rateSourceCoinmetrics = "coinmetrics"
+	rateSourceBinance     = "binance"
+	rateSourceCoinGecko   = "coingecko"
+	rateSourceCSV         = "csv"
+
+	defaultRateMaxDeviation = 0.1
 
 	// dust value can be found increasing the amount value until we get false
 	// from IsDustAmount function. Amounts can not be lower than dust
@@ -91,14 +116,31 @@ type config struct {
 	MailUser                 string `long:"mailuser" description:"Email server username"`
 	MailPass                 string `long:"mailpass" description:"Email server password"`
 	SMTP                     *goemail.SMTP
-	FetchIdentity            bool   `long:"fetchidentity" description:"Whether or not cmswww fetches the identity from politeiad."`
-	WebServerAddress         string `long:"webserveraddress" description:"Address for the Politeia web server; it should have this format: <scheme>://<host>[:<port>]"`
-	Interactive              string `long:"interactive" description:"Set to i-know-this-is-a-bad-idea to turn off interactive mode during --fetchidentity."`
-	CockroachDBName          string `long:"cockroachdbname" description:"The cockroachdb database name"`
-	CockroachDBUsername      string `long:"cockroachdbusername" descrption:"The cockroachdb database username"`
-	CockroachDBHost          string `long:"cockroachdbhost" descrption:"The cockroachdb host; format: <address>:<port>"`
-	MinConfirmationsRequired uint64 `long:"minconfirmations" description:"Minimum blocks confirmation for accepting a payment as paid."`
+	FetchIdentity            bool     `long:"fetchidentity" description:"Whether or not cmswww fetches the identity from politeiad."`
+	WebServerAddress         string   `long:"webserveraddress" description:"Address for the Politeia web server; it should have this format: <scheme>://<host>[:<port>]"`
+	Interactive              string   `long:"interactive" description:"Set to i-know-this-is-a-bad-idea to turn off interactive mode during --fetchidentity."`
+	CockroachDBName          string   `long:"cockroachdbname" description:"The cockroachdb database name"`
+	CockroachDBUsername      string   `long:"cockroachdbusername" descrption:"The cockroachdb database username"`
+	CockroachDBHost          string   `long:"cockroachdbhost" descrption:"The cockroachdb host; format: <address>:<port>"`
+	MinConfirmationsRequired uint64   `long:"minconfirmations" description:"Minimum blocks confirmation for accepting a payment as paid."`
+	PaymentPollWorkers       int      `long:"paymentpollworkers" description:"Number of invoice payments which are checked concurrently"`
+	PaymentTolerance         float64  `long:"paymenttolerance" description:"Fraction of the quoted amount which may be missing from a payment for the invoice to still be considered paid, e.g. 0.001 for 0.1%"`
+	ChainWatcher             string   `long:"chainwatcher" description:"Backend used to detect invoice payments {explorer, dcrrpc, memory}"`
+	DcrdRPCHost              string   `long:"dcrdrpchost" description:"Host of the dcrd or dcrwallet RPC server used by the dcrrpc chain watcher; dcrd must be running with --addrindex"`
+	DcrdRPCUser              string   `long:"dcrdrpcuser" description:"dcrd RPC user name"`
+	DcrdRPCPass              string   `long:"dcrdrpcpass" description:"dcrd RPC password"`
+	DcrdRPCCert              string   `long:"dcrdrpccert" description:"File containing the dcrd RPC certificate"`
+	RateSources              []string `long:"ratesource" description:"Source of the monthly DCR/USD rate {coinmetrics, binance, coingecko, csv}; may be specified multiple times (default coinmetrics, binance and coingecko)"`
+	CoinmetricsURL           string   `long:"coinmetricsurl" description:"Base URL of the Coin Metrics API"`
+	BinanceURL               string   `long:"binanceurl" description:"Base URL of the Binance API"`
+	CoinGeckoURL             string   `long:"coingeckourl" description:"Base URL of the CoinGecko API"`
+	RateCSVFile              string   `long:"ratecsvfile" description:"CSV file of daily DCR prices used by the csv rate source; each row is <YYYY-MM-DD>,<price>[,<currency>] and the currency defaults to USD"`
+	RateMaxDeviation         float64  `long:"ratemaxdeviation" description:"Fraction of the median rate by which the rate of a source may deviate before it's rejected as an outlier; 0 disables outlier rejection"`
+	AdminTOTP                bool     `long:"admintotp" description:"Require admins and users with roles to enable two-factor authentication before they can use privileged routes"`
+	InvoiceApprovals         uint     `long:"invoiceapprovals" description:"Number of distinct reviewers who must approve an invoice before it's approved; the reviewers can't pay the invoices they approved"`
 	AdminLogFile             string
+	LockDuration             time.Duration `long:"lockduration" description:"Time after which an account locked by failed login attempts is unlocked; doubled for each consecutive lock, 0 disables automatic unlocking"`
+	MaxLockDuration          time.Duration `long:"maxlockduration" description:"Maximum time an account locked by failed login attempts stays locked"`
 }
 
 // serviceOptions defines the configuration options for the rpc as a service
@@ -333,6 +375,16 @@ func loadConfig() (*config, []string, error) {
 		CockroachDBUsername:      sharedconfig.DefaultDBUsername,
 		CockroachDBHost:          sharedconfig.DefaultDBHost,
 		MinConfirmationsRequired: defaultPaymentMinConfirmations,
+		PaymentPollWorkers:       defaultPaymentPollWorkers,
+		PaymentTolerance:         defaultPaymentTolerance,
+		ChainWatcher:             chainWatcherExplorer,
+		CoinmetricsURL:           coinmetrics.DefaultURL,
+		BinanceURL:               binance.DefaultURL,
+		CoinGeckoURL:             coingecko.DefaultURL,
+		RateMaxDeviation:         defaultRateMaxDeviation,
+		InvoiceApprovals:         defaultInvoiceApprovals,
+		LockDuration:             defaultLockDuration,
+		MaxLockDuration:          defaultMaxLockDuration,
 		Version:                  version(),
 	}
 
@@ -623,6 +675,113 @@ func loadConfig() (*config, []string, error) {
 		log.Warnf("RPC password not set, using random value")
 	}
 
+	if cfg.PaymentPollWorkers < 1 {
+		err := fmt.Errorf("%s: paymentpollworkers must be at least 1",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.PaymentTolerance < 0 || cfg.PaymentTolerance >= 1 {
+		err := fmt.Errorf("%s: paymenttolerance must be at least 0 and "+
+			"less than 1", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.InvoiceApprovals == 0 {
+		err := fmt.Errorf("%s: invoiceapprovals must be at least 1",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Validate the chain watcher options.
+	switch cfg.ChainWatcher {
+	case chainWatcherExplorer:
+	case chainWatcherMemory:
+		// The in-memory watcher never sees any txs, so invoices would never
+		// be marked as paid.
+		if !cfg.TestNet && !cfg.SimNet {
+			err := fmt.Errorf("%s: the %v chain watcher can only be "+
+				"used on testnet or simnet", funcName, chainWatcherMemory)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	case chainWatcherDcrRPC:
+		if cfg.DcrdRPCHost == "" {
+			cfg.DcrdRPCHost = "localhost"
+		}
+		cfg.DcrdRPCHost = util.NormalizeAddress(cfg.DcrdRPCHost,
+			activeNetParams.DcrdRPCServerPort)
+		u, err := url.Parse("https://" + cfg.DcrdRPCHost)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.DcrdRPCHost = u.String()
+
+		if cfg.DcrdRPCCert != "" {
+			cfg.DcrdRPCCert = cleanAndExpandPath(cfg.DcrdRPCCert)
+		}
+	default:
+		err := fmt.Errorf("%s: invalid chain watcher %v -- choose one "+
+			"of %v, %v or %v", funcName, cfg.ChainWatcher,
+			chainWatcherExplorer, chainWatcherDcrRPC, chainWatcherMemory)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Validate the rate source options.
+	if len(cfg.RateSources) == 0 {
+		cfg.RateSources = []string{
+			rateSourceCoinmetrics,
+			rateSourceBinance,
+			rateSourceCoinGecko,
+		}
+	}
+	for _, source := range cfg.RateSources {
+		switch source {
+		case rateSourceCoinmetrics, rateSourceBinance, rateSourceCoinGecko:
+		case rateSourceCSV:
+			if cfg.RateCSVFile == "" {
+				err := fmt.Errorf("%s: the csv rate source requires "+
+					"ratecsvfile", funcName)
+				fmt.Fprintln(os.Stderr, err)
+				fmt.Fprintln(os.Stderr, usageMessage)
+				return nil, nil, err
+			}
+			cfg.RateCSVFile = cleanAndExpandPath(cfg.RateCSVFile)
+		default:
+			err := fmt.Errorf("%s: invalid rate source %v -- choose from "+
+				"%v, %v, %v and %v", funcName, source, rateSourceCoinmetrics,
+				rateSourceBinance, rateSourceCoinGecko, rateSourceCSV)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	}
+
+	if cfg.RateMaxDeviation < 0 || cfg.RateMaxDeviation >= 1 {
+		err := fmt.Errorf("%s: ratemaxdeviation must be at least 0 and "+
+			"less than 1", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.LockDuration < 0 || cfg.MaxLockDuration < cfg.LockDuration {
+		err := fmt.Errorf("%s: lockduration must be at least 0 and at "+
+			"most maxlockduration", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	if err := initSMTP(&cfg); err != nil {
 		return nil, nil, err
 	}
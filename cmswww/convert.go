@@ -14,19 +14,46 @@ import (
 )
 
 type BackendInvoiceMetadata struct {
-	Version   uint64 `json:"version"` // BackendInvoiceMetadata version
-	Month     uint16 `json:"month"`
-	Year      uint16 `json:"year"`
-	Timestamp int64  `json:"timestamp"` // Last update of invoice
-	PublicKey string `json:"publickey"` // Key used for signature.
-	Signature string `json:"signature"` // Signature of merkle root
+	Version        uint64 `json:"version"` // BackendInvoiceMetadata version
+	Month          uint16 `json:"month"`
+	Year           uint16 `json:"year"`
+	Timestamp      int64  `json:"timestamp"`      // Last update of invoice
+	PublicKey      string `json:"publickey"`      // Key used for signature.
+	Signature      string `json:"signature"`      // Signature of merkle root
+	InvoiceVersion uint64 `json:"invoiceversion"` // Invoice version, incremented on each revision
 }
 
 type BackendInvoiceMDChanges struct {
-	Version        uint              `json:"version"`        // Version of the struct
-	AdminPublicKey string            `json:"adminpublickey"` // Identity of the administrator
-	NewStatus      v1.InvoiceStatusT `json:"newstatus"`      // Status
-	Timestamp      int64             `json:"timestamp"`      // Timestamp of the change
+	Version        uint              `json:"version"`          // Version of the struct
+	AdminPublicKey string            `json:"adminpublickey"`   // Identity of the administrator
+	NewStatus      v1.InvoiceStatusT `json:"newstatus"`        // Status
+	Reason         string            `json:"reason,omitempty"` // Admin reason for the change
+	Timestamp      int64             `json:"timestamp"`        // Timestamp of the change
+
+	// Version 3 fields; older approvals were recorded as changes to the
+	// approved status.
+	Signature string `json:"signature,omitempty"` // Admin signature of the change
+	Approval  bool   `json:"approval,omitempty"`  // Whether the change records an approval
+}
+
+type BackendInvoiceLineItemReview struct {
+	Version        uint               `json:"version"`        // Version of the struct
+	InvoiceVersion uint64             `json:"invoiceversion"` // Invoice version the decision applies to
+	AdminPublicKey string             `json:"adminpublickey"` // Identity of the administrator
+	LineNumber     uint64             `json:"linenumber"`     // Line item position, starting at 1
+	Status         v1.LineItemStatusT `json:"status"`         // Decision
+	Note           string             `json:"note,omitempty"` // Admin note for the decision
+	Timestamp      int64              `json:"timestamp"`      // Timestamp of the decision
+}
+
+type BackendInvoiceComment struct {
+	Version   uint   `json:"version"`   // Version of the struct
+	CommentID uint64 `json:"commentid"` // Comment ID, unique per invoice
+	ParentID  uint64 `json:"parentid"`  // ID of the comment being replied to; 0 if none
+	Comment   string `json:"comment"`   // Comment text
+	PublicKey string `json:"publickey"` // Key used for signature
+	Signature string `json:"signature"` // Signature of Token+string(ParentID)+Comment
+	Timestamp int64  `json:"timestamp"` // Timestamp of the comment
 }
 
 func convertDatabaseUserToUser(user *database.User) v1.User {
@@ -44,7 +71,12 @@ func convertDatabaseUserToUser(user *database.User) v1.User {
 		UpdateIdentityVerificationExpiry: user.UpdateIdentityVerificationExpiry,
 		LastLogin:                        user.LastLogin,
 		FailedLoginAttempts:              user.FailedLoginAttempts,
-		Locked:                           IsUserLocked(user.FailedLoginAttempts),
+		Locked:                           IsUserLocked(user),
+		LockedUntil:                      user.LockedUntil,
+		HourlyRate:                       user.HourlyRate,
+		WorkTypeHourlyRates:              user.WorkTypeHourlyRates,
+		TOTPEnabled:                      user.TOTPVerified,
+		Roles:                            user.Roles,
 		Identities:                       convertDatabaseIdentitiesToIdentities(user.Identities),
 	}
 }
@@ -64,13 +96,23 @@ func convertDatabaseIdentityToIdentity(dbIdentity database.Identity) v1.UserIden
 	}
 }
 
-func convertInvoiceFileFromWWW(f *v1.File) []pd.File {
-	return []pd.File{{
-		Name:    "invoice.csv",
-		MIME:    f.MIME,
+func convertInvoiceFilesFromWWW(f *v1.File, attachments []v1.File) []pd.File {
+	name, mime := convertInvoiceMIMEToPD(f.MIME)
+	files := []pd.File{{
+		Name:    name,
+		MIME:    mime,
 		Digest:  f.Digest,
 		Payload: f.Payload,
 	}}
+	for _, attachment := range attachments {
+		files = append(files, pd.File{
+			Name:    attachment.Name,
+			MIME:    attachment.MIME,
+			Digest:  attachment.Digest,
+			Payload: attachment.Payload,
+		})
+	}
+	return files
 }
 
 func convertInvoiceCensorFromWWW(f v1.CensorshipRecord) pd.CensorshipRecord {
@@ -82,27 +124,66 @@ func convertInvoiceCensorFromWWW(f v1.CensorshipRecord) pd.CensorshipRecord {
 }
 
 func convertInvoiceFileFromPD(files []pd.File) *v1.File {
-	if len(files) == 0 {
-		return nil
+	for _, f := range files {
+		if !isInvoiceFilename(f.Name) {
+			continue
+		}
+
+		return &v1.File{
+			Name:    f.Name,
+			MIME:    convertInvoiceMIMEFromPD(&f),
+			Digest:  f.Digest,
+			Payload: f.Payload,
+		}
 	}
+	return nil
+}
 
-	return &v1.File{
-		MIME:    files[0].MIME,
-		Digest:  files[0].Digest,
-		Payload: files[0].Payload,
+func convertInvoiceAttachmentsFromPD(files []pd.File) []v1.File {
+	attachments := make([]v1.File, 0, len(files))
+	for _, f := range files {
+		if isInvoiceFilename(f.Name) {
+			continue
+		}
+
+		attachments = append(attachments, v1.File{
+			Name:    f.Name,
+			MIME:    f.MIME,
+			Digest:  f.Digest,
+			Payload: f.Payload,
+		})
 	}
+	sortInvoiceAttachments(attachments)
+	return attachments
 }
 
 func convertRecordFilesToDatabaseInvoiceFile(files []pd.File) *database.File {
-	if len(files) == 0 {
-		return nil
+	for _, f := range files {
+		if !isInvoiceFilename(f.Name) {
+			continue
+		}
+
+		return &database.File{
+			Name:    f.Name,
+			MIME:    convertInvoiceMIMEFromPD(&f),
+			Digest:  f.Digest,
+			Payload: f.Payload,
+		}
 	}
+	return nil
+}
 
-	return &database.File{
-		MIME:    files[0].MIME,
-		Digest:  files[0].Digest,
-		Payload: files[0].Payload,
+func convertRecordFilesToDatabaseInvoiceAttachments(files []pd.File) []database.File {
+	attachments := make([]database.File, 0, len(files))
+	for _, attachment := range convertInvoiceAttachmentsFromPD(files) {
+		attachments = append(attachments, database.File{
+			Name:    attachment.Name,
+			MIME:    attachment.MIME,
+			Digest:  attachment.Digest,
+			Payload: attachment.Payload,
+		})
 	}
+	return attachments
 }
 
 func convertInvoiceCensorFromPD(f pd.CensorshipRecord) v1.CensorshipRecord {
@@ -152,7 +233,9 @@ func convertRecordToInvoice(p pd.Record) v1.InvoiceRecord {
 		Year:             md.Year,
 		PublicKey:        md.PublicKey,
 		Signature:        md.Signature,
+		Version:          convertInvoiceVersionFromMD(md),
 		File:             convertInvoiceFileFromPD(p.Files),
+		Attachments:      convertInvoiceAttachmentsFromPD(p.Files),
 		CensorshipRecord: convertInvoiceCensorFromPD(p.CensorshipRecord),
 	}
 }
@@ -160,6 +243,7 @@ func convertRecordToInvoice(p pd.Record) v1.InvoiceRecord {
 func (c *cmswww) convertRecordToDatabaseInvoice(p pd.Record) (*database.Invoice, error) {
 	dbInvoice := database.Invoice{
 		File:            convertRecordFilesToDatabaseInvoiceFile(p.Files),
+		Attachments:     convertRecordFilesToDatabaseInvoiceAttachments(p.Files),
 		Token:           p.CensorshipRecord.Token,
 		ServerSignature: p.CensorshipRecord.Signature,
 	}
@@ -178,6 +262,7 @@ func (c *cmswww) convertRecordToDatabaseInvoice(p pd.Record) (*database.Invoice,
 			dbInvoice.Timestamp = mdGeneral.Timestamp
 			dbInvoice.PublicKey = mdGeneral.PublicKey
 			dbInvoice.UserSignature = mdGeneral.Signature
+			dbInvoice.Version = convertInvoiceVersionFromMD(&mdGeneral)
 
 			dbInvoice.UserID, err = c.db.GetUserIdByPublicKey(mdGeneral.PublicKey)
 			if err != nil {
@@ -199,6 +284,23 @@ func (c *cmswww) convertRecordToDatabaseInvoice(p pd.Record) (*database.Invoice,
 					convertStreamChangeToDatabaseInvoiceChange(mdChanges))
 				dbInvoice.Status = mdChanges.NewStatus
 			}
+		case mdStreamLineItemReviews:
+			f := strings.NewReader(m.Payload)
+			d := json.NewDecoder(f)
+			for {
+				var mdReview BackendInvoiceLineItemReview
+				if err := d.Decode(&mdReview); err == io.EOF {
+					break
+				} else if err != nil {
+					return nil, err
+				}
+
+				dbInvoice.LineItemReviews = append(dbInvoice.LineItemReviews,
+					convertStreamLineItemReviewToDatabaseLineItemReview(mdReview))
+			}
+		case mdStreamComments:
+			// Comments are stored separately from the invoice, see
+			// convertRecordToDatabaseInvoiceComments.
 		default:
 			// Log error but proceed
 			log.Errorf("initializeInventory: invalid "+
@@ -210,17 +312,86 @@ func (c *cmswww) convertRecordToDatabaseInvoice(p pd.Record) (*database.Invoice,
 	return &dbInvoice, nil
 }
 
+func convertStreamLineItemReviewToDatabaseLineItemReview(mdReview BackendInvoiceLineItemReview) database.InvoiceLineItemReview {
+	return database.InvoiceLineItemReview{
+		InvoiceVersion: mdReview.InvoiceVersion,
+		AdminPublicKey: mdReview.AdminPublicKey,
+		LineNumber:     mdReview.LineNumber,
+		Status:         mdReview.Status,
+		Note:           mdReview.Note,
+		Timestamp:      mdReview.Timestamp,
+	}
+}
+
+func (c *cmswww) convertRecordToDatabaseInvoiceComments(p pd.Record) ([]database.InvoiceComment, error) {
+	var dbInvoiceComments []database.InvoiceComment
+	for _, m := range p.Metadata {
+		if m.ID != mdStreamComments {
+			continue
+		}
+
+		f := strings.NewReader(m.Payload)
+		d := json.NewDecoder(f)
+		for {
+			var mdComment BackendInvoiceComment
+			if err := d.Decode(&mdComment); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+
+			userID, err := c.db.GetUserIdByPublicKey(mdComment.PublicKey)
+			if err != nil {
+				return nil, fmt.Errorf("could not get user id from public key %v",
+					mdComment.PublicKey)
+			}
+
+			dbInvoiceComments = append(dbInvoiceComments,
+				convertStreamCommentToDatabaseInvoiceComment(
+					p.CensorshipRecord.Token, userID, mdComment))
+		}
+	}
+
+	return dbInvoiceComments, nil
+}
+
+func convertStreamCommentToDatabaseInvoiceComment(token string, userID uint64, mdComment BackendInvoiceComment) database.InvoiceComment {
+	return database.InvoiceComment{
+		InvoiceToken: token,
+		CommentID:    mdComment.CommentID,
+		ParentID:     mdComment.ParentID,
+		UserID:       userID,
+		Comment:      mdComment.Comment,
+		PublicKey:    mdComment.PublicKey,
+		Signature:    mdComment.Signature,
+		Timestamp:    mdComment.Timestamp,
+	}
+}
+
+// convertInvoiceVersionFromMD returns the invoice version stored in the
+// general metadata. Metadata prior to version 2 did not track invoice
+// versions, so those invoices are always at their first version.
+func convertInvoiceVersionFromMD(md *BackendInvoiceMetadata) uint64 {
+	if md.InvoiceVersion == 0 {
+		return 1
+	}
+	return md.InvoiceVersion
+}
+
 func convertStreamChangeToDatabaseInvoiceChange(mdChanges BackendInvoiceMDChanges) database.InvoiceChange {
 	dbInvoiceChange := database.InvoiceChange{}
 
 	dbInvoiceChange.AdminPublicKey = mdChanges.AdminPublicKey
 	dbInvoiceChange.NewStatus = mdChanges.NewStatus
+	dbInvoiceChange.Reason = mdChanges.Reason
 	dbInvoiceChange.Timestamp = mdChanges.Timestamp
+	dbInvoiceChange.Signature = mdChanges.Signature
+	dbInvoiceChange.Approval = mdChanges.Approval
 
 	return dbInvoiceChange
 }
 
-func convertDatabaseInvoiceToInvoice(dbInvoice *database.Invoice) *v1.InvoiceRecord {
+func convertDatabaseInvoiceToInvoice(dbInvoice *database.Invoice, approvalsRequired uint) *v1.InvoiceRecord {
 	invoice := v1.InvoiceRecord{}
 
 	invoice.Status = dbInvoice.Status
@@ -231,13 +402,23 @@ func convertDatabaseInvoiceToInvoice(dbInvoice *database.Invoice) *v1.InvoiceRec
 	invoice.Username = dbInvoice.Username
 	invoice.PublicKey = dbInvoice.PublicKey
 	invoice.Signature = dbInvoice.UserSignature
+	invoice.Version = dbInvoice.Version
 	if dbInvoice.File != nil {
 		invoice.File = &v1.File{
+			Name:    dbInvoice.File.Name,
 			MIME:    dbInvoice.File.MIME,
 			Digest:  dbInvoice.File.Digest,
 			Payload: dbInvoice.File.Payload,
 		}
 	}
+	for _, attachment := range dbInvoice.Attachments {
+		invoice.Attachments = append(invoice.Attachments, v1.File{
+			Name:    attachment.Name,
+			MIME:    attachment.MIME,
+			Digest:  attachment.Digest,
+			Payload: attachment.Payload,
+		})
+	}
 	invoice.CensorshipRecord = v1.CensorshipRecord{
 		Token: dbInvoice.Token,
 		//Merkle:    dbInvoice.File.Digest,
@@ -245,21 +426,112 @@ func convertDatabaseInvoiceToInvoice(dbInvoice *database.Invoice) *v1.InvoiceRec
 	}
 
 	// TODO: clean up, merkle should always be set
-	if dbInvoice.File != nil {
-		invoice.CensorshipRecord.Merkle = dbInvoice.File.Digest
+	if invoice.File != nil {
+		merkleRoot, err := invoiceMerkleRoot(invoice.File, invoice.Attachments)
+		if err != nil {
+			log.Errorf("could not compute merkle root for invoice %v: %v",
+				dbInvoice.Token, err)
+			merkleRoot = invoice.File.Digest
+		}
+		invoice.CensorshipRecord.Merkle = merkleRoot
 	}
 
+	invoice.Changes = convertDatabaseInvoiceChangesToInvoiceChanges(
+		dbInvoice.Changes)
+	invoice.Payments = convertDatabaseInvoicePaymentsToPaymentRecords(
+		dbInvoice.Payments)
+	invoice.ApprovalsRequired = approvalsRequired
+	invoice.Approvers = invoiceApprovers(dbInvoice)
+
 	return &invoice
 }
 
-func convertDatabaseInvoicesToInvoices(dbInvoices []database.Invoice) []v1.InvoiceRecord {
+func convertDatabaseInvoicePaymentsToPaymentRecords(dbInvoicePayments []database.InvoicePayment) []v1.PaymentRecord {
+	payments := make([]v1.PaymentRecord, 0, len(dbInvoicePayments))
+	for _, dbInvoicePayment := range dbInvoicePayments {
+		payments = append(payments, v1.PaymentRecord{
+			Address:        dbInvoicePayment.Address,
+			Amount:         dbInvoicePayment.Amount,
+			TxNotBefore:    dbInvoicePayment.TxNotBefore,
+			TxIDs:          dbInvoicePayment.TxIDs,
+			Confirmations:  dbInvoicePayment.Confirmations,
+			AmountReceived: dbInvoicePayment.AmountReceived,
+			DetectedAt:     dbInvoicePayment.DetectedAt,
+		})
+	}
+	return payments
+}
+
+func convertDatabaseMonthlyRateToMonthlyRate(dbMonthlyRate *database.MonthlyRate) v1.MonthlyRateRecord {
+	monthlyRate := v1.MonthlyRateRecord{
+		Month:        dbMonthlyRate.Month,
+		Year:         dbMonthlyRate.Year,
+		Currency:     dbMonthlyRate.Currency,
+		DCRRateCents: dbMonthlyRate.DCRRateCents,
+		Sources:      make([]v1.MonthlyRateSource, 0, len(dbMonthlyRate.Sources)),
+		Timestamp:    dbMonthlyRate.Timestamp,
+		Locked:       dbMonthlyRate.Locked,
+		LockedBy:     dbMonthlyRate.LockedBy,
+		LockedAt:     dbMonthlyRate.LockedAt,
+	}
+	for _, source := range dbMonthlyRate.Sources {
+		monthlyRate.Sources = append(monthlyRate.Sources, v1.MonthlyRateSource{
+			Name:         source.Name,
+			DCRRateCents: source.DCRRateCents,
+			Outlier:      source.Outlier,
+			Error:        source.Error,
+		})
+	}
+	return monthlyRate
+}
+
+func convertDatabaseInvoiceChangesToInvoiceChanges(dbInvoiceChanges []database.InvoiceChange) []v1.InvoiceChange {
+	invoiceChanges := make([]v1.InvoiceChange, 0, len(dbInvoiceChanges))
+	for _, dbInvoiceChange := range dbInvoiceChanges {
+		invoiceChanges = append(invoiceChanges, v1.InvoiceChange{
+			AdminPublicKey: dbInvoiceChange.AdminPublicKey,
+			NewStatus:      dbInvoiceChange.NewStatus,
+			Reason:         dbInvoiceChange.Reason,
+			Timestamp:      dbInvoiceChange.Timestamp,
+			Signature:      dbInvoiceChange.Signature,
+			Approval:       dbInvoiceChange.Approval,
+		})
+	}
+	return invoiceChanges
+}
+
+func convertDatabaseInvoicesToInvoices(dbInvoices []database.Invoice, approvalsRequired uint) []v1.InvoiceRecord {
 	invoices := make([]v1.InvoiceRecord, 0, len(dbInvoices))
 	for _, dbInvoice := range dbInvoices {
-		invoices = append(invoices, *convertDatabaseInvoiceToInvoice(&dbInvoice))
+		invoices = append(invoices, *convertDatabaseInvoiceToInvoice(&dbInvoice,
+			approvalsRequired))
 	}
 	return invoices
 }
 
+func convertDatabaseInvoiceCommentToInvoiceComment(dbInvoiceComment *database.InvoiceComment) *v1.InvoiceComment {
+	return &v1.InvoiceComment{
+		Token:     dbInvoiceComment.InvoiceToken,
+		CommentID: dbInvoiceComment.CommentID,
+		ParentID:  dbInvoiceComment.ParentID,
+		Comment:   dbInvoiceComment.Comment,
+		UserID:    strconv.FormatUint(dbInvoiceComment.UserID, 10),
+		Username:  dbInvoiceComment.Username,
+		PublicKey: dbInvoiceComment.PublicKey,
+		Signature: dbInvoiceComment.Signature,
+		Timestamp: dbInvoiceComment.Timestamp,
+	}
+}
+
+func convertDatabaseInvoiceCommentsToInvoiceComments(dbInvoiceComments []database.InvoiceComment) []v1.InvoiceComment {
+	invoiceComments := make([]v1.InvoiceComment, 0, len(dbInvoiceComments))
+	for _, dbInvoiceComment := range dbInvoiceComments {
+		invoiceComments = append(invoiceComments,
+			*convertDatabaseInvoiceCommentToInvoiceComment(&dbInvoiceComment))
+	}
+	return invoiceComments
+}
+
 func convertErrorStatusFromPD(s int) v1.ErrorStatusT {
 	switch pd.ErrorStatusT(s) {
 	case pd.ErrorStatusInvalidFileDigest:
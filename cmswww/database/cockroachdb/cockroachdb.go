@@ -70,7 +70,10 @@ func (c *cockroachdb) CreateUser(dbUser *database.User) error {
 		return database.ErrShutdown
 	}
 
-	user := EncodeUser(dbUser)
+	user, err := EncodeUser(dbUser)
+	if err != nil {
+		return err
+	}
 	log.Debugf("NewUser: %v", user.Email)
 
 	if err := checkmail.ValidateFormat(user.Email); err != nil {
@@ -91,9 +94,39 @@ func (c *cockroachdb) UpdateUser(dbUser *database.User) error {
 		return database.ErrShutdown
 	}
 
-	user := EncodeUser(dbUser)
+	user, err := EncodeUser(dbUser)
+	if err != nil {
+		return err
+	}
 	log.Debugf("UpdateUser: %v", user.Email)
-	return c.db.Model(&User{}).Updates(*user).Error
+
+	tx := c.db.Begin()
+	err = tx.Model(&User{}).Updates(*user).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Updates skips zero values, so the columns which are cleared or reset
+	// to zero are updated explicitly.
+	err = tx.Table(tableNameUser).Where("id = ?", user.ID).Updates(
+		map[string]interface{}{
+			"register_verification_token":         user.RegisterVerificationToken,
+			"register_verification_expiry":        user.RegisterVerificationExpiry,
+			"update_identity_verification_token":  user.UpdateIdentityVerificationToken,
+			"update_identity_verification_expiry": user.UpdateIdentityVerificationExpiry,
+			"reset_password_verification_token":   user.ResetPasswordVerificationToken,
+			"reset_password_verification_expiry":  user.ResetPasswordVerificationExpiry,
+			"failed_login_attempts":               user.FailedLoginAttempts,
+			"locked_until":                        user.LockedUntil,
+			"lockouts":                            user.Lockouts,
+		}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
 }
 
 // GetUser returns a user record if found in the database.
@@ -241,7 +274,121 @@ func (c *cockroachdb) UpdateInvoice(dbInvoice *database.Invoice) error {
 
 	log.Debugf("UpdateInvoice: %v", invoice.Token)
 
-	return c.db.Save(invoice).Error
+	// The changes, payments, line item reviews and attachments are always
+	// rewritten in full, so the existing ones are deleted first to avoid
+	// duplicating them. The deletes are unscoped, since soft deleted rows
+	// would pile up with every update.
+	tx := c.db.Begin()
+	err := tx.Unscoped().Where("invoice_token = ?", invoice.Token).Delete(
+		InvoiceChange{}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Unscoped().Where("invoice_token = ?", invoice.Token).Delete(
+		InvoicePayment{}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Unscoped().Where("invoice_token = ?", invoice.Token).Delete(
+		InvoiceLineItemReview{}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Unscoped().Where("invoice_token = ?", invoice.Token).Delete(
+		InvoiceAttachment{}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Save(invoice).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// UpdateInvoiceFiles stores the file and attachments of an invoice without
+// touching its changes, payments or line item reviews. Nothing is stored if
+// the invoice has been revised since it was read.
+//
+// UpdateInvoiceFiles satisfies the backend interface.
+func (c *cockroachdb) UpdateInvoiceFiles(dbInvoice *database.Invoice) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.shutdown {
+		return database.ErrShutdown
+	}
+
+	invoice := EncodeInvoice(dbInvoice)
+
+	log.Debugf("UpdateInvoiceFiles: %v", invoice.Token)
+
+	tx := c.db.Begin()
+	result := tx.Table(tableNameInvoice).Where("token = ? AND version = ?",
+		invoice.Token, invoice.Version).Updates(
+		map[string]interface{}{
+			"file_name":    invoice.FileName,
+			"file_payload": invoice.FilePayload,
+			"file_mime":    invoice.FileMIME,
+			"file_digest":  invoice.FileDigest,
+		})
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return nil
+	}
+
+	err := tx.Unscoped().Where("invoice_token = ?", invoice.Token).Delete(
+		InvoiceAttachment{}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, attachment := range invoice.Attachments {
+		err = tx.Create(&attachment).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// loadInvoiceAssociations fetches the changes, payments, line item reviews
+// and attachments for the given invoice; they're not populated when reading
+// invoices with a join.
+func (c *cockroachdb) loadInvoiceAssociations(invoice *Invoice) error {
+	err := c.db.Where("invoice_token = ?", invoice.Token).Order("id").Find(
+		&invoice.Changes).Error
+	if err != nil {
+		return err
+	}
+
+	err = c.db.Where("invoice_token = ?", invoice.Token).Order("id").Find(
+		&invoice.Payments).Error
+	if err != nil {
+		return err
+	}
+
+	err = c.db.Where("invoice_token = ?", invoice.Token).Order("id").Find(
+		&invoice.LineItemReviews).Error
+	if err != nil {
+		return err
+	}
+
+	return c.db.Where("invoice_token = ?", invoice.Token).Order("name").Find(
+		&invoice.Attachments).Error
 }
 
 // Return invoice by its token.
@@ -266,6 +413,11 @@ func (c *cockroachdb) GetInvoiceByToken(token string) (*database.Invoice, error)
 		return nil, result.Error
 	}
 
+	err := c.loadInvoiceAssociations(&invoice)
+	if err != nil {
+		return nil, err
+	}
+
 	return DecodeInvoice(&invoice)
 }
 
@@ -317,9 +469,159 @@ func (c *cockroachdb) GetInvoices(invoicesRequest database.InvoicesRequest) ([]d
 		return nil, result.Error
 	}
 
+	for idx := range invoices {
+		err = c.loadInvoiceAssociations(&invoices[idx])
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return DecodeInvoices(invoices)
 }
 
+// Create new invoice comment.
+//
+// CreateInvoiceComment satisfies the backend interface.
+func (c *cockroachdb) CreateInvoiceComment(dbInvoiceComment *database.InvoiceComment) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.shutdown {
+		return database.ErrShutdown
+	}
+
+	invoiceComment := EncodeInvoiceComment(dbInvoiceComment)
+
+	log.Debugf("CreateInvoiceComment: %v %v", invoiceComment.InvoiceToken,
+		invoiceComment.CommentID)
+	return c.db.Create(invoiceComment).Error
+}
+
+// Return all comments on an invoice, ordered by comment id.
+//
+// GetInvoiceComments satisfies the backend interface.
+func (c *cockroachdb) GetInvoiceComments(token string) ([]database.InvoiceComment, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("GetInvoiceComments: %v", token)
+
+	var invoiceComments []InvoiceComment
+	result := c.db.Table(fmt.Sprintf("%v c", tableNameInvoiceComment)).Select(
+		"c.*, u.username").Joins(
+		fmt.Sprintf("inner join %v u on c.user_id = u.id", tableNameUser)).Where(
+		"c.invoice_token = ?", token).Order("c.comment_id").Scan(&invoiceComments)
+	if result.Error != nil && !gorm.IsRecordNotFoundError(result.Error) {
+		return nil, result.Error
+	}
+
+	dbInvoiceComments := make([]database.InvoiceComment, 0,
+		len(invoiceComments))
+	for _, invoiceComment := range invoiceComments {
+		dbInvoiceComments = append(dbInvoiceComments,
+			*DecodeInvoiceComment(&invoiceComment))
+	}
+
+	return dbInvoiceComments, nil
+}
+
+// Create or update the budget of a proposal.
+//
+// SetProposalBudget satisfies the backend interface.
+func (c *cockroachdb) SetProposalBudget(dbProposalBudget *database.ProposalBudget) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.shutdown {
+		return database.ErrShutdown
+	}
+
+	proposalBudget := EncodeProposalBudget(dbProposalBudget)
+
+	log.Debugf("SetProposalBudget: %v", proposalBudget.Proposal)
+	return c.db.Save(proposalBudget).Error
+}
+
+// Return the budgets of all proposals.
+//
+// GetProposalBudgets satisfies the backend interface.
+func (c *cockroachdb) GetProposalBudgets() ([]database.ProposalBudget, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("GetProposalBudgets")
+
+	var proposalBudgets []ProposalBudget
+	err := c.db.Order("proposal").Find(&proposalBudgets).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	dbProposalBudgets := make([]database.ProposalBudget, 0,
+		len(proposalBudgets))
+	for _, proposalBudget := range proposalBudgets {
+		dbProposalBudgets = append(dbProposalBudgets,
+			*DecodeProposalBudget(&proposalBudget))
+	}
+
+	return dbProposalBudgets, nil
+}
+
+// Create or update the DCR rate of a month in a currency.
+//
+// SetMonthlyRate satisfies the backend interface.
+func (c *cockroachdb) SetMonthlyRate(dbMonthlyRate *database.MonthlyRate) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.shutdown {
+		return database.ErrShutdown
+	}
+
+	monthlyRate, err := EncodeMonthlyRate(dbMonthlyRate)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("SetMonthlyRate: %v/%v %v", monthlyRate.Month, monthlyRate.Year,
+		monthlyRate.Currency)
+	return c.db.Save(monthlyRate).Error
+}
+
+// Return the DCR rate of a month in a currency.
+//
+// GetMonthlyRate satisfies the backend interface.
+func (c *cockroachdb) GetMonthlyRate(month, year uint16, currency string) (*database.MonthlyRate, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.shutdown {
+		return nil, database.ErrShutdown
+	}
+
+	log.Debugf("GetMonthlyRate: %v/%v %v", month, year, currency)
+
+	var monthlyRate MonthlyRate
+	err := c.db.Where("month = ? AND year = ? AND currency = ?", month, year,
+		currency).First(&monthlyRate).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, database.ErrMonthlyRateNotFound
+		}
+		return nil, err
+	}
+
+	return DecodeMonthlyRate(&monthlyRate)
+}
+
 // Deletes all data from all tables.
 //
 // DeleteAllData satisfies the backend interface.
@@ -333,6 +635,11 @@ func (c *cockroachdb) DeleteAllData() error {
 
 	log.Debugf("DeleteAllData")
 
+	c.dropTable(tableNameMonthlyRate)
+	c.dropTable(tableNameProposalBudget)
+	c.dropTable(tableNameInvoiceAttachment)
+	c.dropTable(tableNameInvoiceLineItemReview)
+	c.dropTable(tableNameInvoiceComment)
 	c.dropTable(tableNameInvoicePayment)
 	c.dropTable(tableNameInvoiceChange)
 	c.dropTable(tableNameInvoice)
@@ -380,6 +687,24 @@ func New(dataDir, dbName, username, host string) (*cockroachdb, error) {
 		db: db,
 	}
 
+	err = c.dropTable(tableNameInvoiceAttachment)
+	if err != nil {
+		return nil, fmt.Errorf("error dropping invoice attachment table: %v",
+			err)
+	}
+	err = c.dropTable(tableNameInvoiceLineItemReview)
+	if err != nil {
+		return nil, fmt.Errorf("error dropping invoice line item review "+
+			"table: %v", err)
+	}
+	err = c.dropTable(tableNameInvoiceComment)
+	if err != nil {
+		return nil, fmt.Errorf("error dropping invoice comment table: %v", err)
+	}
+	err = c.dropTable(tableNameInvoiceChange)
+	if err != nil {
+		return nil, fmt.Errorf("error dropping invoice change table: %v", err)
+	}
 	err = c.dropTable(tableNameInvoice)
 	if err != nil {
 		return nil, fmt.Errorf("error dropping invoice table: %v", err)
@@ -390,6 +715,11 @@ func New(dataDir, dbName, username, host string) (*cockroachdb, error) {
 		&Invoice{},
 		&InvoiceChange{},
 		&InvoicePayment{},
+		&InvoiceComment{},
+		&InvoiceLineItemReview{},
+		&InvoiceAttachment{},
+		&ProposalBudget{},
+		&MonthlyRate{},
 	)
 
 	return &c, nil
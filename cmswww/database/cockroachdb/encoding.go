@@ -2,6 +2,8 @@ package cockroachdb
 
 import (
 	"encoding/hex"
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/decred/contractor-mgmt/cmswww/api/v1"
@@ -9,7 +11,7 @@ import (
 )
 
 // EncodeUser encodes a database.User instance into a cockroachdb User.
-func EncodeUser(dbUser *database.User) *User {
+func EncodeUser(dbUser *database.User) (*User, error) {
 	user := User{}
 
 	user.ID = uint(dbUser.ID)
@@ -19,6 +21,33 @@ func EncodeUser(dbUser *database.User) *User {
 	user.ExtendedPublicKey = dbUser.ExtendedPublicKey
 	user.Admin = dbUser.Admin
 	user.FailedLoginAttempts = dbUser.FailedLoginAttempts
+	user.Lockouts = dbUser.Lockouts
+	user.PaymentAddressIndex = dbUser.PaymentAddressIndex
+
+	rates, err := json.Marshal(hourlyRates{
+		Default:   dbUser.HourlyRate,
+		WorkTypes: dbUser.WorkTypeHourlyRates,
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.HourlyRates = string(rates)
+
+	totp, err := json.Marshal(userTOTP{
+		Secret:   dbUser.TOTPSecret,
+		Verified: dbUser.TOTPVerified,
+		LastStep: dbUser.TOTPLastStep,
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.TOTP = string(totp)
+
+	roles, err := json.Marshal(dbUser.Roles)
+	if err != nil {
+		return nil, err
+	}
+	user.Roles = string(roles)
 
 	if len(dbUser.Username) > 0 {
 		user.Username.Valid = true
@@ -59,11 +88,16 @@ func EncodeUser(dbUser *database.User) *User {
 		user.LastLogin.Time = time.Unix(dbUser.LastLogin, 0)
 	}
 
+	if dbUser.LockedUntil != 0 {
+		user.LockedUntil.Valid = true
+		user.LockedUntil.Time = time.Unix(dbUser.LockedUntil, 0)
+	}
+
 	for _, dbId := range dbUser.Identities {
 		user.Identities = append(user.Identities, *EncodeIdentity(&dbId))
 	}
 
-	return &user
+	return &user, nil
 }
 
 // DecodeUser decodes a cockroachdb User instance into a generic database.User.
@@ -77,10 +111,42 @@ func DecodeUser(user *User) (*database.User, error) {
 		ExtendedPublicKey:   user.ExtendedPublicKey,
 		Admin:               user.Admin,
 		FailedLoginAttempts: user.FailedLoginAttempts,
+		Lockouts:            user.Lockouts,
+		PaymentAddressIndex: user.PaymentAddressIndex,
 	}
 
 	var err error
 
+	if user.HourlyRates != "" {
+		var rates hourlyRates
+		err = json.Unmarshal([]byte(user.HourlyRates), &rates)
+		if err != nil {
+			return nil, err
+		}
+
+		dbUser.HourlyRate = rates.Default
+		dbUser.WorkTypeHourlyRates = rates.WorkTypes
+	}
+
+	if user.TOTP != "" {
+		var totp userTOTP
+		err = json.Unmarshal([]byte(user.TOTP), &totp)
+		if err != nil {
+			return nil, err
+		}
+
+		dbUser.TOTPSecret = totp.Secret
+		dbUser.TOTPVerified = totp.Verified
+		dbUser.TOTPLastStep = totp.LastStep
+	}
+
+	if user.Roles != "" {
+		err = json.Unmarshal([]byte(user.Roles), &dbUser.Roles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if len(user.HashedPassword.String) > 0 {
 		dbUser.HashedPassword, err = hex.DecodeString(user.HashedPassword.String)
 		if err != nil {
@@ -116,6 +182,10 @@ func DecodeUser(user *User) (*database.User, error) {
 		dbUser.LastLogin = user.LastLogin.Time.Unix()
 	}
 
+	if user.LockedUntil.Valid {
+		dbUser.LockedUntil = user.LockedUntil.Time.Unix()
+	}
+
 	for _, id := range user.Identities {
 		dbId, err := DecodeIdentity(&id)
 		if err != nil {
@@ -191,6 +261,7 @@ func EncodeInvoice(dbInvoice *database.Invoice) *Invoice {
 	invoice.Status = uint(dbInvoice.Status)
 	invoice.Timestamp = time.Unix(dbInvoice.Timestamp, 0)
 	if dbInvoice.File != nil {
+		invoice.FileName = dbInvoice.File.Name
 		invoice.FilePayload = dbInvoice.File.Payload
 		invoice.FileMIME = dbInvoice.File.MIME
 		invoice.FileDigest = dbInvoice.File.Digest
@@ -199,18 +270,38 @@ func EncodeInvoice(dbInvoice *database.Invoice) *Invoice {
 	invoice.UserSignature = dbInvoice.UserSignature
 	invoice.ServerSignature = dbInvoice.ServerSignature
 	invoice.Proposal = dbInvoice.Proposal
+	invoice.Version = uint(dbInvoice.Version)
 
 	for _, dbInvoiceChange := range dbInvoice.Changes {
 		invoiceChange := EncodeInvoiceChange(&dbInvoiceChange)
+		invoiceChange.InvoiceToken = invoice.Token
 		invoice.Changes = append(invoice.Changes, *invoiceChange)
 		invoice.Status = invoiceChange.NewStatus
 	}
 
 	for _, dbInvoicePayment := range dbInvoice.Payments {
 		invoicePayment := EncodeInvoicePayment(&dbInvoicePayment)
+		invoicePayment.InvoiceToken = invoice.Token
 		invoice.Payments = append(invoice.Payments, *invoicePayment)
 	}
 
+	for _, dbLineItemReview := range dbInvoice.LineItemReviews {
+		lineItemReview := EncodeInvoiceLineItemReview(&dbLineItemReview)
+		lineItemReview.InvoiceToken = invoice.Token
+		invoice.LineItemReviews = append(invoice.LineItemReviews,
+			*lineItemReview)
+	}
+
+	for _, dbAttachment := range dbInvoice.Attachments {
+		invoice.Attachments = append(invoice.Attachments, InvoiceAttachment{
+			InvoiceToken: invoice.Token,
+			Name:         dbAttachment.Name,
+			MIME:         dbAttachment.MIME,
+			Digest:       dbAttachment.Digest,
+			Payload:      dbAttachment.Payload,
+		})
+	}
+
 	return &invoice
 }
 
@@ -221,7 +312,10 @@ func EncodeInvoiceChange(dbInvoiceChange *database.InvoiceChange) *InvoiceChange
 
 	invoiceChange.AdminPublicKey = dbInvoiceChange.AdminPublicKey
 	invoiceChange.NewStatus = uint(dbInvoiceChange.NewStatus)
+	invoiceChange.Reason = dbInvoiceChange.Reason
 	invoiceChange.Timestamp = time.Unix(dbInvoiceChange.Timestamp, 0)
+	invoiceChange.Signature = dbInvoiceChange.Signature
+	invoiceChange.Approval = dbInvoiceChange.Approval
 
 	return &invoiceChange
 }
@@ -233,9 +327,15 @@ func EncodeInvoicePayment(dbInvoicePayment *database.InvoicePayment) *InvoicePay
 
 	invoicePayment.Address = dbInvoicePayment.Address
 	invoicePayment.Amount = uint(dbInvoicePayment.Amount)
+	invoicePayment.DCRUSDRateCents = uint(dbInvoicePayment.DCRUSDRateCents)
+	invoicePayment.Currency = dbInvoicePayment.Currency
+	invoicePayment.DCRRateCents = uint(dbInvoicePayment.DCRRateCents)
 	invoicePayment.TxNotBefore = dbInvoicePayment.TxNotBefore
 	invoicePayment.PollExpiry = dbInvoicePayment.PollExpiry
-	invoicePayment.TxID = dbInvoicePayment.TxID
+	invoicePayment.TxIDs = strings.Join(dbInvoicePayment.TxIDs, ",")
+	invoicePayment.Confirmations = uint(dbInvoicePayment.Confirmations)
+	invoicePayment.AmountReceived = uint(dbInvoicePayment.AmountReceived)
+	invoicePayment.DetectedAt = dbInvoicePayment.DetectedAt
 
 	return &invoicePayment
 }
@@ -253,6 +353,7 @@ func DecodeInvoice(invoice *Invoice) (*database.Invoice, error) {
 	dbInvoice.Timestamp = invoice.Timestamp.Unix()
 	if invoice.FilePayload != "" {
 		dbInvoice.File = &database.File{
+			Name:    invoice.FileName,
 			Payload: invoice.FilePayload,
 			MIME:    invoice.FileMIME,
 			Digest:  invoice.FileDigest,
@@ -262,17 +363,32 @@ func DecodeInvoice(invoice *Invoice) (*database.Invoice, error) {
 	dbInvoice.UserSignature = invoice.UserSignature
 	dbInvoice.ServerSignature = invoice.ServerSignature
 	dbInvoice.Proposal = invoice.Proposal
-	/*
-		for _, invoiceChange := range invoice.Changes {
-			dbInvoiceChange := DecodeInvoiceChange(&invoiceChange)
-			dbInvoice.Changes = append(dbInvoice.Changes, *dbInvoiceChange)
-		}
-	*/
+	dbInvoice.Version = uint64(invoice.Version)
+	for _, invoiceChange := range invoice.Changes {
+		dbInvoiceChange := DecodeInvoiceChange(&invoiceChange)
+		dbInvoice.Changes = append(dbInvoice.Changes, *dbInvoiceChange)
+	}
+
 	for _, invoicePayment := range invoice.Payments {
 		dbInvoicePayment := DecodeInvoicePayment(&invoicePayment)
 		dbInvoice.Payments = append(dbInvoice.Payments, *dbInvoicePayment)
 	}
 
+	for _, lineItemReview := range invoice.LineItemReviews {
+		dbLineItemReview := DecodeInvoiceLineItemReview(&lineItemReview)
+		dbInvoice.LineItemReviews = append(dbInvoice.LineItemReviews,
+			*dbLineItemReview)
+	}
+
+	for _, attachment := range invoice.Attachments {
+		dbInvoice.Attachments = append(dbInvoice.Attachments, database.File{
+			Name:    attachment.Name,
+			MIME:    attachment.MIME,
+			Digest:  attachment.Digest,
+			Payload: attachment.Payload,
+		})
+	}
+
 	return &dbInvoice, nil
 }
 
@@ -283,7 +399,10 @@ func DecodeInvoiceChange(invoiceChange *InvoiceChange) *database.InvoiceChange {
 
 	dbInvoiceChange.AdminPublicKey = invoiceChange.AdminPublicKey
 	dbInvoiceChange.NewStatus = v1.InvoiceStatusT(invoiceChange.NewStatus)
+	dbInvoiceChange.Reason = invoiceChange.Reason
 	dbInvoiceChange.Timestamp = invoiceChange.Timestamp.Unix()
+	dbInvoiceChange.Signature = invoiceChange.Signature
+	dbInvoiceChange.Approval = invoiceChange.Approval
 
 	return &dbInvoiceChange
 }
@@ -295,13 +414,159 @@ func DecodeInvoicePayment(invoicePayment *InvoicePayment) *database.InvoicePayme
 
 	dbInvoicePayment.Address = invoicePayment.Address
 	dbInvoicePayment.Amount = uint64(invoicePayment.Amount)
+	dbInvoicePayment.DCRUSDRateCents = uint64(invoicePayment.DCRUSDRateCents)
+	dbInvoicePayment.Currency = invoicePayment.Currency
+	dbInvoicePayment.DCRRateCents = uint64(invoicePayment.DCRRateCents)
 	dbInvoicePayment.TxNotBefore = invoicePayment.TxNotBefore
 	dbInvoicePayment.PollExpiry = invoicePayment.PollExpiry
-	dbInvoicePayment.TxID = invoicePayment.TxID
+	if invoicePayment.TxIDs != "" {
+		dbInvoicePayment.TxIDs = strings.Split(invoicePayment.TxIDs, ",")
+	}
+	dbInvoicePayment.Confirmations = uint64(invoicePayment.Confirmations)
+	dbInvoicePayment.AmountReceived = uint64(invoicePayment.AmountReceived)
+	dbInvoicePayment.DetectedAt = invoicePayment.DetectedAt
 
 	return &dbInvoicePayment
 }
 
+// EncodeInvoiceLineItemReview encodes a generic
+// database.InvoiceLineItemReview instance into a cockroachdb
+// InvoiceLineItemReview.
+func EncodeInvoiceLineItemReview(dbLineItemReview *database.InvoiceLineItemReview) *InvoiceLineItemReview {
+	lineItemReview := InvoiceLineItemReview{}
+
+	lineItemReview.InvoiceVersion = uint(dbLineItemReview.InvoiceVersion)
+	lineItemReview.AdminPublicKey = dbLineItemReview.AdminPublicKey
+	lineItemReview.LineNumber = uint(dbLineItemReview.LineNumber)
+	lineItemReview.Status = uint(dbLineItemReview.Status)
+	lineItemReview.Note = dbLineItemReview.Note
+	lineItemReview.Timestamp = time.Unix(dbLineItemReview.Timestamp, 0)
+
+	return &lineItemReview
+}
+
+// DecodeInvoiceLineItemReview decodes a cockroachdb InvoiceLineItemReview
+// instance into a generic database.InvoiceLineItemReview.
+func DecodeInvoiceLineItemReview(lineItemReview *InvoiceLineItemReview) *database.InvoiceLineItemReview {
+	dbLineItemReview := database.InvoiceLineItemReview{}
+
+	dbLineItemReview.InvoiceVersion = uint64(lineItemReview.InvoiceVersion)
+	dbLineItemReview.AdminPublicKey = lineItemReview.AdminPublicKey
+	dbLineItemReview.LineNumber = uint64(lineItemReview.LineNumber)
+	dbLineItemReview.Status = v1.LineItemStatusT(lineItemReview.Status)
+	dbLineItemReview.Note = lineItemReview.Note
+	dbLineItemReview.Timestamp = lineItemReview.Timestamp.Unix()
+
+	return &dbLineItemReview
+}
+
+// EncodeInvoiceComment encodes a generic database.InvoiceComment instance
+// into a cockroachdb InvoiceComment.
+func EncodeInvoiceComment(dbInvoiceComment *database.InvoiceComment) *InvoiceComment {
+	invoiceComment := InvoiceComment{}
+
+	invoiceComment.InvoiceToken = dbInvoiceComment.InvoiceToken
+	invoiceComment.CommentID = uint(dbInvoiceComment.CommentID)
+	invoiceComment.ParentID = uint(dbInvoiceComment.ParentID)
+	invoiceComment.UserID = uint(dbInvoiceComment.UserID)
+	invoiceComment.Comment = dbInvoiceComment.Comment
+	invoiceComment.PublicKey = dbInvoiceComment.PublicKey
+	invoiceComment.Signature = dbInvoiceComment.Signature
+	invoiceComment.Timestamp = time.Unix(dbInvoiceComment.Timestamp, 0)
+
+	return &invoiceComment
+}
+
+// DecodeInvoiceComment decodes a cockroachdb InvoiceComment instance into a
+// generic database.InvoiceComment.
+func DecodeInvoiceComment(invoiceComment *InvoiceComment) *database.InvoiceComment {
+	dbInvoiceComment := database.InvoiceComment{}
+
+	dbInvoiceComment.InvoiceToken = invoiceComment.InvoiceToken
+	dbInvoiceComment.CommentID = uint64(invoiceComment.CommentID)
+	dbInvoiceComment.ParentID = uint64(invoiceComment.ParentID)
+	dbInvoiceComment.UserID = uint64(invoiceComment.UserID)
+	dbInvoiceComment.Username = invoiceComment.Username
+	dbInvoiceComment.Comment = invoiceComment.Comment
+	dbInvoiceComment.PublicKey = invoiceComment.PublicKey
+	dbInvoiceComment.Signature = invoiceComment.Signature
+	dbInvoiceComment.Timestamp = invoiceComment.Timestamp.Unix()
+
+	return &dbInvoiceComment
+}
+
+// EncodeProposalBudget encodes a generic database.ProposalBudget instance into
+// a cockroachdb ProposalBudget.
+func EncodeProposalBudget(dbProposalBudget *database.ProposalBudget) *ProposalBudget {
+	proposalBudget := ProposalBudget{}
+
+	proposalBudget.Proposal = dbProposalBudget.Proposal
+	proposalBudget.BudgetUSD = uint(dbProposalBudget.BudgetUSD)
+	proposalBudget.Timestamp = time.Unix(dbProposalBudget.Timestamp, 0)
+
+	return &proposalBudget
+}
+
+// EncodeMonthlyRate encodes a generic database.MonthlyRate instance into a
+// cockroachdb MonthlyRate.
+func EncodeMonthlyRate(dbMonthlyRate *database.MonthlyRate) (*MonthlyRate, error) {
+	monthlyRate := MonthlyRate{}
+
+	sources, err := json.Marshal(dbMonthlyRate.Sources)
+	if err != nil {
+		return nil, err
+	}
+
+	monthlyRate.Month = uint(dbMonthlyRate.Month)
+	monthlyRate.Year = uint(dbMonthlyRate.Year)
+	monthlyRate.Currency = dbMonthlyRate.Currency
+	monthlyRate.DCRRateCents = uint(dbMonthlyRate.DCRRateCents)
+	monthlyRate.Sources = string(sources)
+	monthlyRate.Timestamp = time.Unix(dbMonthlyRate.Timestamp, 0)
+	monthlyRate.Locked = dbMonthlyRate.Locked
+	monthlyRate.LockedBy = dbMonthlyRate.LockedBy
+	monthlyRate.LockedAt = dbMonthlyRate.LockedAt
+
+	return &monthlyRate, nil
+}
+
+// DecodeMonthlyRate decodes a cockroachdb MonthlyRate instance into a generic
+// database.MonthlyRate.
+func DecodeMonthlyRate(monthlyRate *MonthlyRate) (*database.MonthlyRate, error) {
+	dbMonthlyRate := database.MonthlyRate{}
+
+	if monthlyRate.Sources != "" {
+		err := json.Unmarshal([]byte(monthlyRate.Sources),
+			&dbMonthlyRate.Sources)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dbMonthlyRate.Month = uint16(monthlyRate.Month)
+	dbMonthlyRate.Year = uint16(monthlyRate.Year)
+	dbMonthlyRate.Currency = monthlyRate.Currency
+	dbMonthlyRate.DCRRateCents = uint64(monthlyRate.DCRRateCents)
+	dbMonthlyRate.Timestamp = monthlyRate.Timestamp.Unix()
+	dbMonthlyRate.Locked = monthlyRate.Locked
+	dbMonthlyRate.LockedBy = monthlyRate.LockedBy
+	dbMonthlyRate.LockedAt = monthlyRate.LockedAt
+
+	return &dbMonthlyRate, nil
+}
+
+// DecodeProposalBudget decodes a cockroachdb ProposalBudget instance into a
+// generic database.ProposalBudget.
+func DecodeProposalBudget(proposalBudget *ProposalBudget) *database.ProposalBudget {
+	dbProposalBudget := database.ProposalBudget{}
+
+	dbProposalBudget.Proposal = proposalBudget.Proposal
+	dbProposalBudget.BudgetUSD = uint64(proposalBudget.BudgetUSD)
+	dbProposalBudget.Timestamp = proposalBudget.Timestamp.Unix()
+
+	return &dbProposalBudget
+}
+
 // DecodeInvoices decodes an array of cockroachdb Invoice instances into
 // generic database.Invoices.
 func DecodeInvoices(invoices []Invoice) ([]database.Invoice, error) {
@@ -10,11 +10,16 @@ import (
 )
 
 const (
-	tableNameUser           = "users"
-	tableNameIdentity       = "identities"
-	tableNameInvoice        = "invoices"
-	tableNameInvoiceChange  = "invoice_changes"
-	tableNameInvoicePayment = "invoice_payments"
+	tableNameUser                  = "users"
+	tableNameIdentity              = "identities"
+	tableNameInvoice               = "invoices"
+	tableNameInvoiceChange         = "invoice_changes"
+	tableNameInvoicePayment        = "invoice_payments"
+	tableNameInvoiceComment        = "invoice_comments"
+	tableNameInvoiceLineItemReview = "invoice_line_item_reviews"
+	tableNameInvoiceAttachment     = "invoice_attachments"
+	tableNameProposalBudget        = "proposal_budgets"
+	tableNameMonthlyRate           = "monthly_rates"
 )
 
 type User struct {
@@ -34,6 +39,12 @@ type User struct {
 	ResetPasswordVerificationExpiry  pq.NullTime
 	LastLogin                        pq.NullTime
 	FailedLoginAttempts              uint64 `gorm:"not_null"`
+	LockedUntil                      pq.NullTime
+	Lockouts                         uint64 `gorm:"not_null"`
+	PaymentAddressIndex              uint64 `gorm:"not_null"`
+	HourlyRates                      string `gorm:"type:text"` // JSON-encoded hourlyRates
+	TOTP                             string `gorm:"type:text"` // JSON-encoded userTOTP
+	Roles                            string `gorm:"type:text"` // JSON-encoded []v1.UserRoleT, always encoded like hourlyRates
 
 	Identities []Identity
 	Invoices   []Invoice
@@ -43,6 +54,21 @@ func (u User) TableName() string {
 	return tableNameUser
 }
 
+// hourlyRates is stored as JSON in the User table. It's always encoded, even
+// when no rates are set, so that removing rates is persisted on update.
+type hourlyRates struct {
+	Default   uint64            `json:"default"`
+	WorkTypes map[string]uint64 `json:"worktypes"`
+}
+
+// userTOTP is stored as JSON in the User table for the same reason as
+// hourlyRates, so that disabling two-factor authentication is persisted.
+type userTOTP struct {
+	Secret   string `json:"secret"`
+	Verified bool   `json:"verified"`
+	LastStep uint64 `json:"laststep"`
+}
+
 type Identity struct {
 	gorm.Model
 	UserID      uint           `gorm:"not_null"`
@@ -64,15 +90,19 @@ type Invoice struct {
 	Timestamp       time.Time `gorm:not_null"`
 	Status          uint      `gorm:"not_null"`
 	FilePayload     string    `gorm:"type:text"`
+	FileName        string
 	FileMIME        string
 	FileDigest      string
 	PublicKey       string `gorm:"not_null"`
 	UserSignature   string `gorm:"not_null"`
 	ServerSignature string `gorm:"not_null"`
 	Proposal        string
+	Version         uint `gorm:"not_null"`
 
-	Changes  []InvoiceChange
-	Payments []InvoicePayment
+	Changes         []InvoiceChange
+	Payments        []InvoicePayment
+	LineItemReviews []InvoiceLineItemReview
+	Attachments     []InvoiceAttachment
 
 	// gorm.Model fields, included manually
 	CreatedAt time.Time
@@ -85,24 +115,106 @@ func (i Invoice) TableName() string {
 }
 
 type InvoiceChange struct {
+	ID             uint   `gorm:"primary_key"`
+	InvoiceToken   string `gorm:"not_null"`
 	AdminPublicKey string
-	NewStatus      uint
+	NewStatus      uint   `gorm:"not_null"`
+	Reason         string `gorm:"type:text"`
 	Timestamp      time.Time
+	Signature      string
+	Approval       bool
 }
 
 func (i InvoiceChange) TableName() string {
 	return tableNameInvoiceChange
 }
 
+type InvoiceLineItemReview struct {
+	ID             uint   `gorm:"primary_key"`
+	InvoiceToken   string `gorm:"not_null"`
+	InvoiceVersion uint   `gorm:"not_null"`
+	AdminPublicKey string `gorm:"not_null"`
+	LineNumber     uint   `gorm:"not_null"`
+	Status         uint   `gorm:"not_null"`
+	Note           string `gorm:"type:text"`
+	Timestamp      time.Time
+}
+
+func (i InvoiceLineItemReview) TableName() string {
+	return tableNameInvoiceLineItemReview
+}
+
+type InvoiceAttachment struct {
+	ID           uint   `gorm:"primary_key"`
+	InvoiceToken string `gorm:"not_null"`
+	Name         string `gorm:"not_null"`
+	MIME         string `gorm:"not_null"`
+	Digest       string `gorm:"not_null"`
+	Payload      string `gorm:"type:text"`
+}
+
+func (i InvoiceAttachment) TableName() string {
+	return tableNameInvoiceAttachment
+}
+
+type InvoiceComment struct {
+	InvoiceToken string `gorm:"primary_key"`
+	CommentID    uint   `gorm:"primary_key;auto_increment:false"`
+	ParentID     uint
+	UserID       uint   `gorm:"not_null"`
+	Username     string `gorm:"-"` // Only populated when reading from the database
+	Comment      string `gorm:"type:text;not_null"`
+	PublicKey    string `gorm:"not_null"`
+	Signature    string `gorm:"not_null"`
+	Timestamp    time.Time
+}
+
+func (i InvoiceComment) TableName() string {
+	return tableNameInvoiceComment
+}
+
 type InvoicePayment struct {
 	gorm.Model
-	Address     string `gorm:"not_null"`
-	Amount      uint   `gorm:"not_null"`
-	TxNotBefore int64  `gorm:"not_null"`
-	PollExpiry  int64
-	TxID        string
+	InvoiceToken    string `gorm:"not_null"`
+	Address         string `gorm:"not_null"`
+	Amount          uint   `gorm:"not_null"`
+	TxNotBefore     int64  `gorm:"not_null"`
+	PollExpiry      int64
+	TxIDs           string `gorm:"type:text"` // Comma separated
+	Confirmations   uint
+	AmountReceived  uint
+	DetectedAt      int64
+	DCRUSDRateCents uint
+	Currency        string
+	DCRRateCents    uint
 }
 
 func (i InvoicePayment) TableName() string {
 	return tableNameInvoicePayment
 }
+
+type ProposalBudget struct {
+	Proposal  string `gorm:"primary_key"`
+	BudgetUSD uint   `gorm:"not_null"`
+	Timestamp time.Time
+}
+
+func (p ProposalBudget) TableName() string {
+	return tableNameProposalBudget
+}
+
+type MonthlyRate struct {
+	Month        uint   `gorm:"primary_key;auto_increment:false"`
+	Year         uint   `gorm:"primary_key;auto_increment:false"`
+	Currency     string `gorm:"primary_key"`
+	DCRRateCents uint   `gorm:"not_null"`
+	Sources      string `gorm:"type:text"` // JSON encoded
+	Timestamp    time.Time
+	Locked       bool `gorm:"not_null"`
+	LockedBy     string
+	LockedAt     int64
+}
+
+func (m MonthlyRate) TableName() string {
+	return tableNameMonthlyRate
+}
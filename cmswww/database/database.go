@@ -27,6 +27,10 @@ var (
 	// ErrInvalidEmail indicates that a user's email is not properly formatted.
 	ErrInvalidEmail = errors.New("invalid user email")
 
+	// ErrMonthlyRateNotFound indicates that no DCR rate has been stored for
+	// a month in a currency.
+	ErrMonthlyRateNotFound = errors.New("monthly rate not found")
+
 	// ErrShutdown is emitted when the database is shutting down.
 	ErrShutdown = errors.New("database is shutting down")
 )
@@ -56,9 +60,22 @@ type Database interface {
 	// Invoice functions
 	CreateInvoice(*Invoice) error                   // Create new invoice
 	UpdateInvoice(*Invoice) error                   // Update existing invoice
+	UpdateInvoiceFiles(*Invoice) error              // Store the file and attachments of an existing invoice
 	GetInvoiceByToken(string) (*Invoice, error)     // Return invoice given its token
 	GetInvoices(InvoicesRequest) ([]Invoice, error) // Return a list of invoices
 
+	// Invoice comment functions
+	CreateInvoiceComment(*InvoiceComment) error          // Create new invoice comment
+	GetInvoiceComments(string) ([]InvoiceComment, error) // Return all comments on an invoice
+
+	// Proposal budget functions
+	SetProposalBudget(*ProposalBudget) error       // Create or update a proposal budget
+	GetProposalBudgets() ([]ProposalBudget, error) // Return all proposal budgets
+
+	// Monthly rate functions
+	SetMonthlyRate(*MonthlyRate) error                           // Create or update the rate of a month
+	GetMonthlyRate(uint16, uint16, string) (*MonthlyRate, error) // Return the rate given the month, year and currency
+
 	DeleteAllData() error // Delete all data from all tables
 
 	// Close performs cleanup of the backend.
@@ -75,6 +92,7 @@ type User struct {
 	ExtendedPublicKey                         string
 	HashedPassword                            []byte
 	Admin                                     bool
+	Roles                                     []v1.UserRoleT // Roles granting part of the privileges of an admin
 	RegisterVerificationToken                 []byte
 	RegisterVerificationExpiry                int64
 	UpdateIdentityVerificationToken           []byte
@@ -85,7 +103,14 @@ type User struct {
 	UpdateExtendedPublicKeyVerificationExpiry int64
 	LastLogin                                 int64
 	FailedLoginAttempts                       uint64
+	LockedUntil                               int64  // Time at which a lock due to failed logins expires; 0 if it doesn't
+	Lockouts                                  uint64 // Consecutive locks due to failed logins, used to back off
 	PaymentAddressIndex                       uint64
+	HourlyRate                                uint64            // Default hourly rate in USD
+	WorkTypeHourlyRates                       map[string]uint64 // Hourly rates in USD keyed by lowercase type of work
+	TOTPSecret                                string            // Base32 encoded TOTP secret; empty if not enrolled
+	TOTPVerified                              bool              // Set once a code has been verified, which enables 2FA
+	TOTPLastStep                              uint64            // Time step of the last accepted code, to prevent replays
 
 	Identities []Identity
 }
@@ -109,16 +134,20 @@ type Invoice struct {
 	Timestamp       int64
 	Status          v1.InvoiceStatusT
 	File            *File
+	Attachments     []File // Receipts for expenses, sorted by name
 	PublicKey       string
 	UserSignature   string
 	ServerSignature string
 	Proposal        string // Optional link to a Politeia proposal
+	Version         uint64 // Incremented each time the invoice is revised
 
-	Changes  []InvoiceChange
-	Payments []InvoicePayment
+	Changes         []InvoiceChange
+	Payments        []InvoicePayment
+	LineItemReviews []InvoiceLineItemReview
 }
 
 type File struct {
+	Name    string
 	Payload string
 	MIME    string
 	Digest  string
@@ -127,15 +156,77 @@ type File struct {
 type InvoiceChange struct {
 	AdminPublicKey string
 	NewStatus      v1.InvoiceStatusT
+	Reason         string
+	Timestamp      int64
+	Signature      string
+	Approval       bool
+}
+
+type InvoiceLineItemReview struct {
+	InvoiceVersion uint64
+	AdminPublicKey string
+	LineNumber     uint64
+	Status         v1.LineItemStatusT
+	Note           string
 	Timestamp      int64
 }
 
+type InvoiceComment struct {
+	InvoiceToken string
+	CommentID    uint64
+	ParentID     uint64 // 0 if the comment is not a reply
+	UserID       uint64
+	Username     string // Only populated when reading from the database
+	Comment      string
+	PublicKey    string
+	Signature    string
+	Timestamp    int64
+}
+
+// ProposalBudget is the maximum amount that can be billed against a Politeia
+// proposal across all invoices.
+type ProposalBudget struct {
+	Proposal  string // Politeia proposal token
+	BudgetUSD uint64 // 0 if there is no budget
+	Timestamp int64  // Last update of the budget
+}
+
+// MonthlyRate is the DCR rate in one currency used to pay the invoices of a
+// month, along with the rates provided by each of the sources it was
+// computed from.
+type MonthlyRate struct {
+	Month        uint16
+	Year         uint16
+	Currency     string
+	DCRRateCents uint64 // Mean of the rates of the sources which aren't outliers, in cents of the currency per DCR
+	Sources      []MonthlyRateSource
+	Timestamp    int64  // Time at which the rate was fetched
+	Locked       bool   // Whether the rate can no longer be fetched again
+	LockedBy     string // Public key of the admin who locked the rate
+	LockedAt     int64
+}
+
+// MonthlyRateSource is the rate provided by a single source, or the error
+// which prevented the source from providing one.
+type MonthlyRateSource struct {
+	Name         string
+	DCRRateCents uint64
+	Outlier      bool // Whether the rate was left out for deviating too much
+	Error        string
+}
+
 type InvoicePayment struct {
-	Address     string
-	Amount      uint64
-	TxNotBefore int64
-	PollExpiry  int64
-	TxID        string
+	Address         string
+	Amount          uint64 // Expected amount in atoms
+	DCRUSDRateCents uint64 // Rate in USD cents per DCR at which the payment was quoted
+	Currency        string // Currency of the invoice when it was quoted; empty for older payments, which are in USD
+	DCRRateCents    uint64 // Rate in cents of Currency per DCR at which the payment was quoted; 0 for older payments
+	TxNotBefore     int64
+	PollExpiry      int64
+	TxIDs           []string // Txs which sent funds to the address
+	Confirmations   uint64   // Fewest confirmations of the txs when last checked
+	AmountReceived  uint64   // Total amount sent to the address by the txs in atoms
+	DetectedAt      int64    // Time at which the last tx was detected
 }
 
 func (id *Identity) IsActive() bool {
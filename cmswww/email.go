@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"html/template"
+	"time"
 
 	"github.com/dajohi/goemail"
 )
@@ -20,6 +21,10 @@ type ResetPasswordEmailTemplateData struct {
 	Token string
 	Email string
 }
+type UserLockedEmailTemplateData struct {
+	Email      string
+	UnlockTime string
+}
 type UpdateExtendedPublicKeyEmailTemplateData struct {
 	Token string
 	Email string
@@ -103,40 +108,34 @@ func (c *cmswww) emailUpdateIdentityVerificationLink(email, publicKey, token str
 }
 
 // emailUserLocked notifies the user its account has been locked and
-// emails the link with the reset password verification token
-// if the email server is set up.
-func (c *cmswww) emailUserLocked(email string) error {
-	//if c.cfg.SMTP == nil {
-	return nil
-	//}
-	/*
-		l, err := url.Parse(c.cfg.WebServerAddress + ResetPasswordGuiRoute)
-		if err != nil {
-			return err
-		}
-		q := l.Query()
-		q.Set("email", email)
-		l.RawQuery = q.Encode()
-
-		var buf bytes.Buffer
-		tplData := resetPasswordEmailTemplateData{
-			Email: email,
-			Link:  l.String(),
-		}
-		err = templateUserLockedResetPassword.Execute(&buf, &tplData)
-		if err != nil {
-			return err
-		}
-		from := "noreply@decred.org"
-		subject := "Locked Account - Reset Your Password"
-		body := buf.String()
-
-		msg := goemail.NewHTMLMessage(from, subject, body)
-		msg.AddTo(email)
-
-		msg.SetName(cmsMailName)
-		return c.cfg.SMTP.Send(msg)
-	*/
+// explains how to reset the password to unlock it, if the email server is
+// set up. The unlock time is 0 if the lock doesn't expire.
+func (c *cmswww) emailUserLocked(email string, lockedUntil int64) error {
+	if c.cfg.SMTP == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	tplData := UserLockedEmailTemplateData{
+		Email: email,
+	}
+	if lockedUntil != 0 {
+		tplData.UnlockTime = time.Unix(lockedUntil, 0).UTC().Format(
+			time.RFC1123)
+	}
+	err := templateUserLockedResetPassword.Execute(&buf, &tplData)
+	if err != nil {
+		return err
+	}
+	from := "noreply@decred.org"
+	subject := "Locked Account - Reset Your Password"
+	body := buf.String()
+
+	msg := goemail.NewHTMLMessage(from, subject, body)
+	msg.AddTo(email)
+
+	msg.SetName(cmsMailName)
+	return c.cfg.SMTP.Send(msg)
 }
 
 func (c *cmswww) emailResetPasswordVerificationLink(email, token string) error {
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strconv"
 
+	"github.com/decred/politeia/politeiad/api/v1/identity"
 	"github.com/decred/politeia/util"
 
 	"github.com/decred/contractor-mgmt/cmswww/database"
@@ -68,6 +69,38 @@ func (c *cmswww) RemoteIdentity() error {
 	return nil
 }
 
+// loadServerIdentity loads the identity which the server signs payout batches
+// with, and creates it the first time the server runs.
+func (c *cmswww) loadServerIdentity() error {
+	filename := filepath.Join(c.cfg.DataDir, serverIdentityFilename)
+
+	var err error
+	if fileExists(filename) {
+		c.identity, err = identity.LoadFullIdentity(filename)
+		if err != nil {
+			return err
+		}
+	} else {
+		c.identity, err = identity.New()
+		if err != nil {
+			return err
+		}
+
+		err = os.MkdirAll(filepath.Dir(filename), 0700)
+		if err != nil {
+			return err
+		}
+		err = c.identity.Save(filename)
+		if err != nil {
+			return err
+		}
+		log.Infof("Server identity saved to: %v", filename)
+	}
+
+	log.Infof("Server public key: %x", c.identity.Public.Key)
+	return nil
+}
+
 // SetUserPubkeyAssociaton associates a public key with a user id in
 // the userPubkeys cache.
 //
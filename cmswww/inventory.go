@@ -27,6 +27,16 @@ func (c *cmswww) updateInventoryRecord(record pd.Record) error {
 		return err
 	}
 
+	// Payments are only stored in the database, so they're carried over
+	// from the existing invoice.
+	existingInvoice, err := c.db.GetInvoiceByToken(dbInvoice.Token)
+	if err != nil && err != database.ErrInvoiceNotFound {
+		return err
+	}
+	if existingInvoice != nil {
+		dbInvoice.Payments = existingInvoice.Payments
+	}
+
 	return c.db.UpdateInvoice(dbInvoice)
 }
 
@@ -39,7 +49,24 @@ func (c *cmswww) newInventoryRecord(record pd.Record) error {
 		return err
 	}
 
-	return c.db.CreateInvoice(dbInvoice)
+	err = c.db.CreateInvoice(dbInvoice)
+	if err != nil {
+		return err
+	}
+
+	dbInvoiceComments, err := c.convertRecordToDatabaseInvoiceComments(record)
+	if err != nil {
+		return err
+	}
+
+	for _, dbInvoiceComment := range dbInvoiceComments {
+		err = c.db.CreateInvoiceComment(&dbInvoiceComment)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // initializeInventory loads the database with the current inventory of Politeia records.
@@ -63,7 +90,8 @@ func (c *cmswww) getInvoice(token string) (*v1.InvoiceRecord, error) {
 		return nil, err
 	}
 
-	return convertDatabaseInvoiceToInvoice(dbInvoice), nil
+	return convertDatabaseInvoiceToInvoice(dbInvoice,
+		c.cfg.InvoiceApprovals), nil
 }
 
 // getInvoices returns a list of invoices that adheres to the requirements
@@ -76,5 +104,6 @@ func (c *cmswww) getInvoices(pr database.InvoicesRequest) ([]v1.InvoiceRecord, e
 		return nil, err
 	}
 
-	return convertDatabaseInvoicesToInvoices(dbInvoices), nil
+	return convertDatabaseInvoicesToInvoices(dbInvoices,
+		c.cfg.InvoiceApprovals), nil
 }
@@ -1,8 +1,6 @@
 package main
 
 import (
-	"encoding/base64"
-	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -27,17 +25,45 @@ func validateStatusTransition(dbInvoice *database.Invoice, newStatus v1.InvoiceS
 			return nil
 		}
 	} else if dbInvoice.Status == v1.InvoiceStatusApproved {
+		if newStatus == v1.InvoiceStatusPaid ||
+			newStatus == v1.InvoiceStatusPartiallyPaid {
+			return nil
+		}
+	} else if dbInvoice.Status == v1.InvoiceStatusPartiallyPaid {
 		if newStatus == v1.InvoiceStatusPaid {
 			return nil
 		}
 	}
 
+	// A rejected invoice goes back into review when a revision of it
+	// is submitted.
+	if dbInvoice.Status == v1.InvoiceStatusRejected &&
+		newStatus == v1.InvoiceStatusNotReviewed {
+		return nil
+	}
+
 	return v1.UserError{
 		ErrorCode: v1.ErrorStatusInvalidInvoiceStatusTransition,
 	}
 }
 
-func (c *cmswww) createInvoiceReview(invoice *database.Invoice) (*v1.InvoiceReview, error) {
+// latestLineItemReviews returns the most recent review decision for each line
+// item in the current version of the invoice, keyed by line number.
+func latestLineItemReviews(invoice *database.Invoice) map[uint64]database.InvoiceLineItemReview {
+	reviews := make(map[uint64]database.InvoiceLineItemReview)
+	for _, review := range invoice.LineItemReviews {
+		if review.InvoiceVersion != invoice.Version {
+			continue
+		}
+		reviews[review.LineNumber] = review
+	}
+	return reviews
+}
+
+// createInvoiceReview returns the line items of an invoice along with their
+// totals. The totals of invoices billed in other currencies are normalized to
+// the base currency with the given DCR rates, keyed by currency.
+func (c *cmswww) createInvoiceReview(invoice *database.Invoice, rates map[string]uint64) (*v1.InvoiceReview, error) {
 	invoiceReview := v1.InvoiceReview{
 		UserID:    strconv.FormatUint(invoice.UserID, 10),
 		Username:  invoice.Username,
@@ -45,101 +71,254 @@ func (c *cmswww) createInvoiceReview(invoice *database.Invoice) (*v1.InvoiceRevi
 		LineItems: make([]v1.InvoiceReviewLineItem, 0, 0),
 	}
 
-	b, err := base64.StdEncoding.DecodeString(invoice.File.Payload)
+	lineItems, currency, err := parseInvoice(invoice.File)
 	if err != nil {
 		return nil, err
 	}
+	invoiceReview.Currency = currency
 
-	csvReader := csv.NewReader(strings.NewReader(string(b)))
-	csvReader.Comma = v1.PolicyInvoiceFieldDelimiterChar
-	csvReader.Comment = v1.PolicyInvoiceCommentChar
-	csvReader.TrimLeadingSpace = true
-
-	records, err := csvReader.ReadAll()
+	user, err := c.db.GetUserById(invoice.UserID)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, record := range records {
-		lineItem := v1.InvoiceReviewLineItem{}
-		for idx := range v1.InvoiceFields {
-			var err error
-			switch idx {
-			case 0:
-				lineItem.Type = record[idx]
-			case 1:
-				lineItem.Subtype = record[idx]
-			case 2:
-				lineItem.Description = record[idx]
-			case 3:
-				lineItem.Proposal = record[idx]
-			case 4:
-				lineItem.Hours, err = strconv.ParseUint(record[idx], 10, 64)
-				if err != nil {
-					return nil, err
-				}
-
-				invoiceReview.TotalHours += lineItem.Hours
-			case 5:
-				lineItem.TotalCost, err = strconv.ParseUint(record[idx], 10, 64)
-				if err != nil {
-					return nil, err
-				}
+	lineItemReviews := latestLineItemReviews(invoice)
+	for idx, lineItem := range lineItems {
+		lineItem.LineNumber = uint64(idx + 1)
+		if review, ok := lineItemReviews[lineItem.LineNumber]; ok {
+			lineItem.Status = review.Status
+			lineItem.Note = review.Note
+		}
 
-				invoiceReview.TotalCostUSD += lineItem.TotalCost
+		// Verify the cost of hourly line items against the contractor's
+		// rate, or compute it if the contractor left it out.
+		rate := contractorHourlyRate(user, lineItem.Type, currency)
+		if lineItem.Kind == v1.LineItemKindHourly && lineItem.Hours > 0 &&
+			rate > 0 {
+			lineItem.Rate = rate
+			expectedCost, ok := mulAmount(lineItem.Hours, rate)
+			lineItem.ExpectedCost = expectedCost
+			if !ok {
+				// A cost which can't be computed never matches.
+				lineItem.CostMismatch = true
+				invoiceReview.CostMismatch = true
+			} else if lineItem.TotalCost == 0 {
+				lineItem.TotalCost = lineItem.ExpectedCost
+			} else if lineItem.TotalCost != lineItem.ExpectedCost {
+				lineItem.CostMismatch = true
+				invoiceReview.CostMismatch = true
 			}
 		}
 
+		// Invoices are validated on submission, but the totals are
+		// still checked so that they can't wrap around.
+		var hoursOK, costOK bool
+		invoiceReview.TotalHours, hoursOK = addAmount(
+			invoiceReview.TotalHours, lineItem.Hours)
+		invoiceReview.TotalCost, costOK = addAmount(
+			invoiceReview.TotalCost, lineItem.TotalCost)
+		if !hoursOK || !costOK {
+			return nil, invalidInvoiceInputError(fmt.Sprintf(
+				"totals of invoice %v overflow", invoice.Token))
+		}
 		invoiceReview.LineItems = append(invoiceReview.LineItems, lineItem)
 	}
 
+	invoiceReview.TotalCostUSD, _ = normalizeAmount(invoiceReview.TotalCost,
+		currency, rates)
+	invoiceReview.Proposals = addProposalTotals(nil, invoiceReview.LineItems,
+		currency, rates)
 	return &invoiceReview, nil
 }
 
-func (c *cmswww) createInvoicePayment(dbInvoice *database.Invoice, dcrUSDRate float64) (*v1.InvoicePayment, error) {
+// addProposalTotals adds the hours and cost of the line items which reference
+// a Politeia proposal to the totals for that proposal, and returns the
+// totals. Disputed line items are left out since they won't be paid, as in
+// the spend of proposals. Proposals are kept in the order they first appear.
+// Costs are normalized from the given currency to the base currency, and left
+// out if there's no rate to normalize them with.
+func addProposalTotals(totals []v1.InvoiceProposalTotal, lineItems []v1.InvoiceReviewLineItem, currency string, rates map[string]uint64) []v1.InvoiceProposalTotal {
+	if totals == nil {
+		totals = make([]v1.InvoiceProposalTotal, 0)
+	}
+
+	for _, lineItem := range lineItems {
+		proposal := strings.TrimSpace(lineItem.Proposal)
+		if proposal == "" ||
+			lineItem.Status == v1.LineItemStatusDisputed {
+			continue
+		}
+
+		idx := -1
+		for i := range totals {
+			if totals[i].Proposal == proposal {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			totals = append(totals, v1.InvoiceProposalTotal{
+				Proposal: proposal,
+			})
+			idx = len(totals) - 1
+		}
+
+		totals[idx].TotalHours += lineItem.Hours
+		cost, _ := normalizeAmount(lineItem.TotalCost, currency, rates)
+		totals[idx].TotalCostUSD += cost
+
+		milestone := strings.TrimSpace(lineItem.Milestone)
+		if milestone == "" {
+			continue
+		}
+		found := false
+		for _, m := range totals[idx].Milestones {
+			if m == milestone {
+				found = true
+				break
+			}
+		}
+		if !found {
+			totals[idx].Milestones = append(totals[idx].Milestones,
+				milestone)
+		}
+	}
+
+	return totals
+}
+
+// outstandingInvoicePayment returns the most recent payment of an invoice if
+// the invoice hasn't been paid in full yet.
+func outstandingInvoicePayment(dbInvoice *database.Invoice) *database.InvoicePayment {
+	if len(dbInvoice.Payments) == 0 ||
+		dbInvoice.Status == v1.InvoiceStatusPaid {
+		return nil
+	}
+
+	return &dbInvoice.Payments[len(dbInvoice.Payments)-1]
+}
+
+// payableTotals returns the hours and cost of the line items of an invoice
+// which are paid. Only approved line items are paid. If no line item of the
+// current version of the invoice has been reviewed, as with invoices which
+// were approved before line items could be reviewed, all of them are paid.
+func payableTotals(dbInvoice *database.Invoice, lineItems []v1.InvoiceReviewLineItem) (uint64, uint64) {
+	var totalHours, totalCost uint64
+	reviewed := len(latestLineItemReviews(dbInvoice)) > 0
+	for _, lineItem := range lineItems {
+		if reviewed && lineItem.Status != v1.LineItemStatusApproved {
+			continue
+		}
+
+		totalHours += lineItem.Hours
+		totalCost += lineItem.TotalCost
+	}
+	return totalHours, totalCost
+}
+
+// createInvoicePayment returns the payment quote for an invoice. An
+// outstanding quote is reused unless requote is set, in which case a new
+// quote is made to a newly derived address at the DCR rate of the invoice
+// currency, out of the given rates. Partially paid invoices are never
+// requoted, since part of the quote has already been sent.
+//
+// This function must be called WITH the mutex held.
+func (c *cmswww) createInvoicePayment(dbInvoice *database.Invoice, rates map[string]uint64, requote bool) (*v1.InvoicePayment, error) {
 	invoicePayment := v1.InvoicePayment{
 		UserID:   strconv.FormatUint(dbInvoice.UserID, 10),
 		Username: dbInvoice.Username,
 		Token:    dbInvoice.Token,
 	}
 
-	b, err := base64.StdEncoding.DecodeString(dbInvoice.File.Payload)
+	invoiceReview, err := c.createInvoiceReview(dbInvoice, rates)
 	if err != nil {
 		return nil, err
 	}
+	invoicePayment.Currency = invoiceReview.Currency
+
+	invoicePayment.TotalHours, invoicePayment.TotalCost = payableTotals(
+		dbInvoice, invoiceReview.LineItems)
+
+	existingPayment := outstandingInvoicePayment(dbInvoice)
+	if existingPayment != nil && (!requote ||
+		dbInvoice.Status == v1.InvoiceStatusPartiallyPaid) {
+		// The amounts are normalized at the rates of the quote, which
+		// may differ from the current ones.
+		currency, quoteRates := paymentRates(existingPayment)
+		totalCostUSD, ok := normalizeAmount(invoicePayment.TotalCost,
+			currency, quoteRates)
+		if !ok {
+			return nil, fmt.Errorf("cannot normalize the cost of invoice "+
+				"%v at the rates of its quote", dbInvoice.Token)
+		}
 
-	csvReader := csv.NewReader(strings.NewReader(string(b)))
-	csvReader.Comma = v1.PolicyInvoiceFieldDelimiterChar
-	csvReader.Comment = v1.PolicyInvoiceCommentChar
-	csvReader.TrimLeadingSpace = true
-
-	records, err := csvReader.ReadAll()
-	if err != nil {
-		return nil, err
-	}
+		// Keep watching the address, since the existing quote is about
+		// to be paid.
+		existingPayment.PollExpiry = time.Now().Add(pollExpiryDuration).Unix()
+		err = c.db.UpdateInvoice(dbInvoice)
+		if err != nil {
+			return nil, err
+		}
 
-	for _, record := range records {
-		for idx := range v1.InvoiceFields {
-			switch idx {
-			case 4:
-				hours, err := strconv.ParseUint(record[idx], 10, 64)
-				if err != nil {
-					return nil, err
-				}
+		c.addInvoiceForPolling(dbInvoice.Token, existingPayment)
 
-				invoicePayment.TotalHours += hours
-			case 5:
-				totalCost, err := strconv.ParseUint(record[idx], 10, 64)
-				if err != nil {
-					return nil, err
-				}
+		invoicePayment.TotalCostUSD = totalCostUSD
+		invoicePayment.TotalCostAtoms = existingPayment.Amount
+		invoicePayment.DCRUSDRateCents = existingPayment.DCRUSDRateCents
+		invoicePayment.DCRRateCents = quoteRates[currency]
+		invoicePayment.PaymentAddress = existingPayment.Address
+		invoicePayment.QuotedAt = existingPayment.TxNotBefore
+		invoicePayment.Existing = true
+		return &invoicePayment, nil
+	}
 
-				invoicePayment.TotalCostUSD += totalCost
+	// The rate of the base currency is needed as well, to normalize the
+	// amount.
+	for _, currency := range []string{invoicePayment.Currency,
+		v1.PolicyInvoiceCurrency} {
+		if rates[currency] == 0 {
+			return nil, v1.UserError{
+				ErrorCode: v1.ErrorStatusMonthlyRateNotLocked,
+				ErrorContext: []string{fmt.Sprintf("a DCR/%v rate is "+
+					"required to quote invoice %v", currency,
+					dbInvoice.Token)},
 			}
 		}
+	}
+	dcrRateCents := rates[invoicePayment.Currency]
 
-		invoicePayment.TotalCostDCR = float64(invoicePayment.TotalCostUSD) / dcrUSDRate
+	amount, err := convertToAtoms(invoicePayment.TotalCost, dcrRateCents)
+	if err != nil {
+		return nil, err
+	}
+	if amount == 0 {
+		// There is nothing to pay, e.g. when every line item is disputed,
+		// and a quote of nothing could never be paid.
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+			ErrorContext: []string{fmt.Sprintf("invoice %v has nothing "+
+				"to pay", dbInvoice.Token)},
+		}
 	}
+	if amount < dust {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+			ErrorContext: []string{fmt.Sprintf("the amount of %v for "+
+				"invoice %v is below the dust limit", dcrutil.Amount(amount),
+				dbInvoice.Token)},
+		}
+	}
+
+	totalCostUSD, ok := normalizeAmount(invoicePayment.TotalCost,
+		invoicePayment.Currency, rates)
+	if !ok {
+		return nil, invalidInvoiceInputError(fmt.Sprintf("the cost of "+
+			"invoice %v is too large to normalize", dbInvoice.Token))
+	}
+	invoicePayment.TotalCostUSD = totalCostUSD
+	invoicePayment.TotalCostAtoms = amount
+	invoicePayment.DCRUSDRateCents = rates[v1.PolicyInvoiceCurrency]
+	invoicePayment.DCRRateCents = dcrRateCents
 
 	// Generate the user's address
 	user, err := c.db.GetUserById(dbInvoice.UserID)
@@ -147,22 +326,28 @@ func (c *cmswww) createInvoicePayment(dbInvoice *database.Invoice, dcrUSDRate fl
 		return nil, err
 	}
 
-	// Create a new invoice payment in the DB.
 	address, txNotBefore, err := c.derivePaymentInfo(user)
 	if err != nil {
 		return nil, err
 	}
 
-	amount, err := dcrutil.NewAmount(invoicePayment.TotalCostDCR)
+	// Each quote is paid to its own address, so the index is moved past
+	// the address which was just derived.
+	user.PaymentAddressIndex++
+	err = c.db.UpdateUser(user)
 	if err != nil {
 		return nil, err
 	}
 
+	// Create a new invoice payment in the DB.
 	dbInvoicePayment := database.InvoicePayment{
-		Address:     address,
-		TxNotBefore: txNotBefore,
-		Amount:      uint64(amount),
-		PollExpiry:  time.Now().Add(pollExpiryDuration).Unix(),
+		Address:         address,
+		TxNotBefore:     txNotBefore,
+		Amount:          amount,
+		DCRUSDRateCents: invoicePayment.DCRUSDRateCents,
+		Currency:        invoicePayment.Currency,
+		DCRRateCents:    dcrRateCents,
+		PollExpiry:      time.Now().Add(pollExpiryDuration).Unix(),
 	}
 	dbInvoice.Payments = append(dbInvoice.Payments, dbInvoicePayment)
 	err = c.db.UpdateInvoice(dbInvoice)
@@ -171,46 +356,179 @@ func (c *cmswww) createInvoicePayment(dbInvoice *database.Invoice, dcrUSDRate fl
 	}
 
 	invoicePayment.PaymentAddress = address
+	invoicePayment.QuotedAt = txNotBefore
 
-	//c.addInvoiceForPollingLock(dbInvoice.Token, &dbInvoicePayment)
+	c.addInvoiceForPolling(dbInvoice.Token, &dbInvoicePayment)
 
 	return &invoicePayment, nil
 }
 
-func (c *cmswww) fetchInvoiceFileIfNecessary(invoice *database.Invoice) error {
-	if invoice.File != nil {
-		return nil
+// setInvoiceStatus records a status change of an invoice in politeiad and
+// then in the database, along with any other changes made to the invoice.
+// The admin public key is empty for changes made by the server itself, such
+// as detected payments.
+func (c *cmswww) setInvoiceStatus(dbInvoice *database.Invoice, adminPublicKey string, newStatus v1.InvoiceStatusT, reason string) error {
+	return c.addInvoiceChange(dbInvoice, BackendInvoiceMDChanges{
+		Version:        VersionBackendInvoiceMDChanges,
+		Timestamp:      time.Now().Unix(),
+		AdminPublicKey: adminPublicKey,
+		NewStatus:      newStatus,
+		Reason:         reason,
+	})
+}
+
+// addInvoiceChange records a change of an invoice in politeiad and then in
+// the database, and updates the status of the invoice to the new status of
+// the change.
+func (c *cmswww) addInvoiceChange(dbInvoice *database.Invoice, changes BackendInvoiceMDChanges) error {
+	err := c.appendVettedMetadata(dbInvoice.Token, mdStreamChanges, changes)
+	if err != nil {
+		return err
+	}
+
+	dbInvoice.Changes = append(dbInvoice.Changes, database.InvoiceChange{
+		Timestamp:      changes.Timestamp,
+		AdminPublicKey: changes.AdminPublicKey,
+		NewStatus:      changes.NewStatus,
+		Reason:         changes.Reason,
+		Signature:      changes.Signature,
+		Approval:       changes.Approval,
+	})
+	dbInvoice.Status = changes.NewStatus
+	return c.db.UpdateInvoice(dbInvoice)
+}
+
+// invoiceApprovers returns the public keys of the admins who approved the
+// current submission of an invoice, in the order of their approvals. Older
+// approvals were recorded as changes to the approved status. A revision or a
+// rejection clears the approvals made before it.
+func invoiceApprovers(dbInvoice *database.Invoice) []string {
+	var approvers []string
+	approved := make(map[string]bool)
+	for _, change := range dbInvoice.Changes {
+		if change.Approval || (change.NewStatus == v1.InvoiceStatusApproved &&
+			change.AdminPublicKey != "") {
+			if !approved[change.AdminPublicKey] {
+				approved[change.AdminPublicKey] = true
+				approvers = append(approvers, change.AdminPublicKey)
+			}
+			continue
+		}
+
+		switch change.NewStatus {
+		case v1.InvoiceStatusNotReviewed, v1.InvoiceStatusRejected:
+			approvers = nil
+			approved = make(map[string]bool)
+		}
 	}
+	return approvers
+}
+
+// isInvoiceApprover returns whether the user approved the current submission
+// of an invoice with any of their identities.
+func isInvoiceApprover(dbInvoice *database.Invoice, user *database.User) bool {
+	for _, approver := range invoiceApprovers(dbInvoice) {
+		for _, id := range user.Identities {
+			if approver == hex.EncodeToString(id.Key[:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
 
+// fetchVettedRecord fetches a record from politeiad. If the version is
+// empty, the latest version of the record is returned.
+func (c *cmswww) fetchVettedRecord(token, version string) (*pd.Record, error) {
 	challenge, err := util.Random(pd.ChallengeSize)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	responseBody, err := c.rpc(http.MethodPost, pd.GetVettedRoute,
 		pd.GetVetted{
-			Token:     invoice.Token,
+			Token:     token,
+			Version:   version,
 			Challenge: hex.EncodeToString(challenge),
 		})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var pdReply pd.GetVettedReply
 	err = json.Unmarshal(responseBody, &pdReply)
 	if err != nil {
-		return fmt.Errorf("Could not unmarshal "+
+		return nil, fmt.Errorf("Could not unmarshal "+
 			"GetVettedReply: %v", err)
 	}
 
 	// Verify the challenge.
 	err = util.VerifyChallenge(c.cfg.Identity, challenge, pdReply.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pdReply.Record, nil
+}
+
+// appendVettedMetadata marshals the provided metadata and appends it to the
+// given metadata stream of a vetted record in politeiad.
+func (c *cmswww) appendVettedMetadata(token string, streamID uint64, md interface{}) error {
+	blob, err := json.Marshal(md)
 	if err != nil {
 		return err
 	}
 
-	invoice.File = convertRecordFilesToDatabaseInvoiceFile(pdReply.Record.Files)
-	return nil
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return err
+	}
+
+	pdCommand := pd.UpdateVettedMetadata{
+		Challenge: hex.EncodeToString(challenge),
+		Token:     token,
+		MDAppend: []pd.MetadataStream{
+			{
+				ID:      streamID,
+				Payload: string(blob),
+			},
+		},
+	}
+
+	responseBody, err := c.rpc(http.MethodPost, pd.UpdateVettedMetadataRoute,
+		pdCommand)
+	if err != nil {
+		return err
+	}
+
+	var pdReply pd.UpdateVettedMetadataReply
+	err = json.Unmarshal(responseBody, &pdReply)
+	if err != nil {
+		return fmt.Errorf("Could not unmarshal UpdateVettedMetadataReply: %v",
+			err)
+	}
+
+	// Verify the challenge.
+	return util.VerifyChallenge(c.cfg.Identity, challenge, pdReply.Response)
+}
+
+// fetchInvoiceFileIfNecessary fetches the file and attachments of an invoice
+// from politeiad if they aren't cached in the database yet, and caches them so
+// that later reads don't have to fetch them again.
+func (c *cmswww) fetchInvoiceFileIfNecessary(invoice *database.Invoice) error {
+	if invoice.File != nil {
+		return nil
+	}
+
+	record, err := c.fetchVettedRecord(invoice.Token, "")
+	if err != nil {
+		return err
+	}
+
+	invoice.File = convertRecordFilesToDatabaseInvoiceFile(record.Files)
+	invoice.Attachments = convertRecordFilesToDatabaseInvoiceAttachments(
+		record.Files)
+	return c.db.UpdateInvoiceFiles(invoice)
 }
 
 // HandleInvoices returns an array of all invoices.
@@ -265,24 +583,68 @@ func (c *cmswww) HandleReviewInvoices(
 	}
 
 	var invoiceReviews []v1.InvoiceReview
+	proposalTotals := make([]v1.InvoiceProposalTotal, 0)
+
+	spending, err := c.getProposalSpending()
+	if err != nil {
+		return nil, err
+	}
 
+	// The rates don't have to be locked yet, since they're only used to
+	// give an idea of the amounts in the base currency.
+	rates, err := c.monthlyRates(ri.Month, ri.Year, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalCostUSD uint64
+	missingRates := make(map[string]bool)
 	for _, invoice := range invoices {
 		err := c.fetchInvoiceFileIfNecessary(&invoice)
 		if err != nil {
 			return nil, err
 		}
 
-		invoiceReview, err := c.createInvoiceReview(&invoice)
+		invoiceReview, err := c.createInvoiceReview(&invoice, rates)
 		if err != nil {
 			return nil, err
 		}
 
+		flagOverBudgetProposals(invoiceReview.Proposals, spending)
+
 		invoiceReviews = append(invoiceReviews, *invoiceReview)
+		proposalTotals = addProposalTotals(proposalTotals,
+			invoiceReview.LineItems, invoiceReview.Currency, rates)
+
+		_, ok := normalizeAmount(invoiceReview.TotalCost,
+			invoiceReview.Currency, rates)
+		if ok {
+			totalCostUSD += invoiceReview.TotalCostUSD
+			continue
+		}
+		for _, currency := range []string{invoiceReview.Currency,
+			v1.PolicyInvoiceCurrency} {
+			if rates[currency] == 0 {
+				missingRates[currency] = true
+			}
+		}
 	}
 
-	return &v1.ReviewInvoicesReply{
-		Invoices: invoiceReviews,
-	}, nil
+	// Also warn when the invoices under review would only push a proposal
+	// over budget together.
+	flagOverBudgetProposals(proposalTotals, spending)
+
+	rir := v1.ReviewInvoicesReply{
+		Invoices:     invoiceReviews,
+		Proposals:    proposalTotals,
+		TotalCostUSD: totalCostUSD,
+	}
+	for _, currency := range v1.PolicyInvoiceCurrencies {
+		if missingRates[currency] {
+			rir.MissingRates = append(rir.MissingRates, currency)
+		}
+	}
+	return &rir, nil
 }
 
 // HandlePayInvoices returns an array of all invoices.
@@ -294,36 +656,81 @@ func (c *cmswww) HandlePayInvoices(
 ) (interface{}, error) {
 	pi := req.(*v1.PayInvoices)
 
+	err := validatePayoutBatchFormat(pi.BatchFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	// The lock is held so that payment addresses are derived sequentially.
+	c.Lock()
+	defer c.Unlock()
+
+	// New quotes are made at the locked rates of the month, unless a USD
+	// rate is given.
+	rates, err := c.monthlyRates(pi.Month, pi.Year, true)
+	if err != nil {
+		return nil, err
+	}
+	if pi.DCRUSDRateCents == 0 {
+		pi.DCRUSDRateCents = rates[v1.PolicyInvoiceCurrency]
+	} else {
+		rates[v1.PolicyInvoiceCurrency] = pi.DCRUSDRateCents
+	}
+
 	invoices, err := c.db.GetInvoices(database.InvoicesRequest{
 		Month: pi.Month,
 		Year:  pi.Year,
 		StatusMap: map[v1.InvoiceStatusT]bool{
-			v1.InvoiceStatusApproved: true,
+			v1.InvoiceStatusApproved:      true,
+			v1.InvoiceStatusPartiallyPaid: true,
 		},
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	invoicePayments := make([]v1.InvoicePayment, 0, 0)
+	pir := v1.PayInvoicesReply{
+		Invoices: make([]v1.InvoicePayment, 0, len(invoices)),
+	}
+	payouts := make([]v1.Payout, 0, len(invoices))
 
 	for _, invoice := range invoices {
+		// Invoices have to be paid by someone other than the admins who
+		// approved them.
+		if isInvoiceApprover(&invoice, user) {
+			pir.ApprovedByPayer = append(pir.ApprovedByPayer,
+				invoice.Token)
+			continue
+		}
+
 		err := c.fetchInvoiceFileIfNecessary(&invoice)
 		if err != nil {
 			return nil, err
 		}
 
-		invoicePayment, err := c.createInvoicePayment(&invoice, pi.DCRUSDRate)
+		invoicePayment, err := c.createInvoicePayment(&invoice, rates,
+			pi.Requote)
 		if err != nil {
 			return nil, err
 		}
 
-		invoicePayments = append(invoicePayments, *invoicePayment)
+		pir.Invoices = append(pir.Invoices, *invoicePayment)
+		pir.TotalCostUSD += invoicePayment.TotalCostUSD
+		pir.TotalCostAtoms += invoicePayment.TotalCostAtoms
+
+		payout := newPayout(&invoice)
+		if payout != nil {
+			payouts = append(payouts, *payout)
+		}
 	}
 
-	return &v1.PayInvoicesReply{
-		Invoices: invoicePayments,
-	}, nil
+	if pi.BatchFormat != "" {
+		pir.Batch, err = c.createPayoutBatch(pi, rates, payouts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &pir, nil
 }
 
 // HandleMyInvoices returns an array of user's invoices.
@@ -356,7 +763,9 @@ func (c *cmswww) HandleMyInvoices(
 }
 
 // HandleSetInvoiceStatus changes the status of an existing invoice
-// from unreviewed to either published or rejected.
+// from unreviewed to either published or rejected. Approvals are recorded
+// until the number required by the server is reached, and only then is the
+// invoice approved.
 func (c *cmswww) HandleSetInvoiceStatus(
 	req interface{},
 	user *database.User,
@@ -365,12 +774,44 @@ func (c *cmswww) HandleSetInvoiceStatus(
 ) (interface{}, error) {
 	sis := req.(*v1.SetInvoiceStatus)
 
+	// Invoices are only moved back into review by submitting a revision.
+	if sis.Status == v1.InvoiceStatusNotReviewed {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInvoiceStatusTransition,
+		}
+	}
+
+	// Reviewers approve and reject invoices, while treasurers mark them as
+	// paid.
+	role := v1.UserRoleReviewer
+	if sis.Status == v1.InvoiceStatusPaid {
+		role = v1.UserRoleTreasurer
+	}
+	if !userHasRole(user, role) {
+		return nil, v1.UserError{
+			ErrorCode:    v1.ErrorStatusMissingUserRole,
+			ErrorContext: []string{v1.UserRole[role]},
+		}
+	}
+
 	err := checkPublicKeyAndSignature(user, sis.PublicKey, sis.Signature,
-		sis.Token, strconv.FormatUint(uint64(sis.Status), 10))
+		sis.Token, strconv.FormatUint(uint64(sis.Status), 10), sis.Reason)
 	if err != nil {
 		return nil, err
 	}
 
+	// Validate that the reason is supplied for rejections.
+	sis.Reason = strings.TrimSpace(sis.Reason)
+	if sis.Status == v1.InvoiceStatusRejected && len(sis.Reason) == 0 {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusReasonNotProvided,
+		}
+	}
+
+	// The lock is held so that concurrent approvals are counted correctly.
+	c.Lock()
+	defer c.Unlock()
+
 	dbInvoice, err := c.db.GetInvoiceByToken(sis.Token)
 	if err != nil {
 		if err == database.ErrInvoiceNotFound {
@@ -387,82 +828,266 @@ func (c *cmswww) HandleSetInvoiceStatus(
 		return nil, err
 	}
 
-	// Create the change record.
+	adminPublicKey, ok := database.ActiveIdentityString(user.Identities)
+	if !ok {
+		return nil, fmt.Errorf("invalid admin identity: %v",
+			user.ID)
+	}
+
 	changes := BackendInvoiceMDChanges{
-		Version:   VersionBackendInvoiceMDChanges,
-		Timestamp: time.Now().Unix(),
-		NewStatus: sis.Status,
+		Version:        VersionBackendInvoiceMDChanges,
+		Timestamp:      time.Now().Unix(),
+		AdminPublicKey: adminPublicKey,
+		NewStatus:      sis.Status,
+		Reason:         sis.Reason,
+		Signature:      sis.Signature,
 	}
+	action := fmt.Sprintf("set invoice status to %v",
+		v1.InvoiceStatus[sis.Status])
+
+	switch sis.Status {
+	case v1.InvoiceStatusApproved:
+		// Each reviewer approves an invoice once, and its status only
+		// changes once the required number of approvals is reached.
+		if isInvoiceApprover(dbInvoice, user) {
+			return nil, v1.UserError{
+				ErrorCode: v1.ErrorStatusDuplicateInvoiceApproval,
+			}
+		}
 
-	var ok bool
-	changes.AdminPublicKey, ok = database.ActiveIdentityString(user.Identities)
+		changes.Approval = true
+		approvals := uint(len(invoiceApprovers(dbInvoice))) + 1
+		if approvals < c.cfg.InvoiceApprovals {
+			changes.NewStatus = dbInvoice.Status
+			action = fmt.Sprintf("approved invoice (%v of %v approvals)",
+				approvals, c.cfg.InvoiceApprovals)
+		}
+	case v1.InvoiceStatusPaid:
+		if isInvoiceApprover(dbInvoice, user) {
+			return nil, v1.UserError{
+				ErrorCode: v1.ErrorStatusInvoiceApprovedByPayer,
+			}
+		}
+	}
+
+	err = c.addInvoiceChange(dbInvoice, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Log the action in the admin log.
+	c.logAdminInvoiceAction(user, sis.Token, action, sis.Reason)
+
+	// Return the reply.
+	sisr := v1.SetInvoiceStatusReply{
+		Invoice: *convertDatabaseInvoiceToInvoice(dbInvoice,
+			c.cfg.InvoiceApprovals),
+	}
+	return &sisr, nil
+}
+
+// HandleReconcileInvoicePayment marks a partially paid invoice as paid, once
+// an admin has settled the difference between the quoted and the received
+// amount.
+func (c *cmswww) HandleReconcileInvoicePayment(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	rip := req.(*v1.ReconcileInvoicePayment)
+
+	err := checkPublicKeyAndSignature(user, rip.PublicKey, rip.Signature,
+		rip.Token, rip.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	rip.Reason = strings.TrimSpace(rip.Reason)
+	if len(rip.Reason) == 0 {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusReasonNotProvided,
+		}
+	}
+
+	// The lock is held so that the reconciliation isn't overwritten by a
+	// concurrent payment update or status change.
+	c.Lock()
+	defer c.Unlock()
+
+	dbInvoice, err := c.db.GetInvoiceByToken(rip.Token)
+	if err != nil {
+		if err == database.ErrInvoiceNotFound {
+			return nil, v1.UserError{
+				ErrorCode: v1.ErrorStatusInvoiceNotFound,
+			}
+		}
+
+		return nil, err
+	}
+
+	if dbInvoice.Status != v1.InvoiceStatusPartiallyPaid {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInvoiceStatusTransition,
+		}
+	}
+	if isInvoiceApprover(dbInvoice, user) {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusInvoiceApprovedByPayer,
+		}
+	}
+
+	adminPublicKey, ok := database.ActiveIdentityString(user.Identities)
 	if !ok {
 		return nil, fmt.Errorf("invalid admin identity: %v",
 			user.ID)
 	}
 
-	blob, err := json.Marshal(changes)
+	err = c.setInvoiceStatus(dbInvoice, adminPublicKey,
+		v1.InvoiceStatusPaid, rip.Reason)
 	if err != nil {
 		return nil, err
 	}
 
-	challenge, err := util.Random(pd.ChallengeSize)
+	// Log the action in the admin log.
+	err = c.logAdminInvoiceAction(user, rip.Token, "reconciled invoice payment",
+		rip.Reason)
 	if err != nil {
 		return nil, err
 	}
 
-	pdCommand := pd.UpdateVettedMetadata{
-		Challenge: hex.EncodeToString(challenge),
-		Token:     sis.Token,
-		MDAppend: []pd.MetadataStream{
-			{
-				ID:      mdStreamChanges,
-				Payload: string(blob),
-			},
-		},
+	return &v1.ReconcileInvoicePaymentReply{
+		Invoice: *convertDatabaseInvoiceToInvoice(dbInvoice,
+			c.cfg.InvoiceApprovals),
+	}, nil
+}
+
+// HandleReviewInvoiceLineItem records an admin's decision to approve or
+// dispute a single line item of an invoice.
+func (c *cmswww) HandleReviewInvoiceLineItem(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	rli := req.(*v1.ReviewInvoiceLineItem)
+
+	err := checkPublicKeyAndSignature(user, rli.PublicKey, rli.Signature,
+		rli.Token, strconv.FormatUint(rli.LineNumber, 10),
+		strconv.FormatUint(uint64(rli.Status), 10), rli.Note)
+	if err != nil {
+		return nil, err
 	}
 
-	responseBody, err := c.rpc(http.MethodPost, pd.UpdateVettedMetadataRoute,
-		pdCommand)
+	if rli.Status != v1.LineItemStatusApproved &&
+		rli.Status != v1.LineItemStatusDisputed {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidLineItemStatus,
+		}
+	}
+
+	// Validate that the note is supplied for disputes.
+	rli.Note = strings.TrimSpace(rli.Note)
+	if rli.Status == v1.LineItemStatusDisputed && len(rli.Note) == 0 {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusReasonNotProvided,
+		}
+	}
+
+	// The lock is held so that concurrent reviews and payment updates
+	// don't overwrite each other.
+	c.Lock()
+	defer c.Unlock()
+
+	dbInvoice, err := c.db.GetInvoiceByToken(rli.Token)
 	if err != nil {
+		if err == database.ErrInvoiceNotFound {
+			return nil, v1.UserError{
+				ErrorCode: v1.ErrorStatusInvoiceNotFound,
+			}
+		}
+
 		return nil, err
 	}
 
-	var pdReply pd.UpdateVettedMetadataReply
-	err = json.Unmarshal(responseBody, &pdReply)
+	// Line items can be reviewed until the invoice is paid, but not while
+	// the contractor is revising it.
+	if dbInvoice.Status != v1.InvoiceStatusNotReviewed &&
+		dbInvoice.Status != v1.InvoiceStatusApproved {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInvoiceStatusTransition,
+		}
+	}
+
+	err = c.fetchInvoiceFileIfNecessary(dbInvoice)
 	if err != nil {
-		return nil, fmt.Errorf("Could not unmarshal SetUnvettedStatusReply: %v",
-			err)
+		return nil, err
 	}
 
-	// Verify the challenge.
-	err = util.VerifyChallenge(c.cfg.Identity, challenge, pdReply.Response)
+	rates, err := c.monthlyRates(dbInvoice.Month, dbInvoice.Year, false)
 	if err != nil {
 		return nil, err
 	}
 
-	// Update the database with the metadata changes.
-	dbInvoice.Changes = append(dbInvoice.Changes, database.InvoiceChange{
-		Timestamp:      changes.Timestamp,
-		AdminPublicKey: changes.AdminPublicKey,
-		NewStatus:      changes.NewStatus,
-	})
-	dbInvoice.Status = changes.NewStatus
+	invoiceReview, err := c.createInvoiceReview(dbInvoice, rates)
+	if err != nil {
+		return nil, err
+	}
+
+	if rli.LineNumber == 0 ||
+		rli.LineNumber > uint64(len(invoiceReview.LineItems)) {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidLineItem,
+		}
+	}
+
+	// Create the review record.
+	review := BackendInvoiceLineItemReview{
+		Version:        VersionBackendInvoiceLineItemReview,
+		InvoiceVersion: dbInvoice.Version,
+		LineNumber:     rli.LineNumber,
+		Status:         rli.Status,
+		Note:           rli.Note,
+		Timestamp:      time.Now().Unix(),
+	}
+
+	var ok bool
+	review.AdminPublicKey, ok = database.ActiveIdentityString(user.Identities)
+	if !ok {
+		return nil, fmt.Errorf("invalid admin identity: %v",
+			user.ID)
+	}
+
+	err = c.appendVettedMetadata(rli.Token, mdStreamLineItemReviews, review)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the database with the review.
+	dbInvoice.LineItemReviews = append(dbInvoice.LineItemReviews,
+		convertStreamLineItemReviewToDatabaseLineItemReview(review))
 	err = c.db.UpdateInvoice(dbInvoice)
 	if err != nil {
 		return nil, err
 	}
 
 	// Log the action in the admin log.
-	c.logAdminInvoiceAction(user, sis.Token,
-		fmt.Sprintf("set invoice status to %v",
-			v1.InvoiceStatus[sis.Status]))
+	err = c.logAdminInvoiceAction(user, rli.Token,
+		fmt.Sprintf("set line item %v status to %v", rli.LineNumber,
+			v1.LineItemStatus[rli.Status]), rli.Note)
+	if err != nil {
+		return nil, err
+	}
 
 	// Return the reply.
-	sisr := v1.SetInvoiceStatusReply{
-		Invoice: *convertDatabaseInvoiceToInvoice(dbInvoice),
+	invoiceReview, err = c.createInvoiceReview(dbInvoice, rates)
+	if err != nil {
+		return nil, err
 	}
-	return &sisr, nil
+
+	return &v1.ReviewInvoiceLineItemReply{
+		Invoice: *invoiceReview,
+	}, nil
 }
 
 // HandleInvoiceDetails tries to fetch the full details of an invoice from
@@ -475,12 +1100,6 @@ func (c *cmswww) HandleInvoiceDetails(
 ) (interface{}, error) {
 	id := req.(*v1.InvoiceDetails)
 
-	var idr v1.InvoiceDetailsReply
-	challenge, err := util.Random(pd.ChallengeSize)
-	if err != nil {
-		return nil, err
-	}
-
 	dbInvoice, err := c.db.GetInvoiceByToken(id.Token)
 	if err != nil {
 		if err == database.ErrInvoiceNotFound {
@@ -491,73 +1110,47 @@ func (c *cmswww) HandleInvoiceDetails(
 		return nil, err
 	}
 
-	invoice := convertDatabaseInvoiceToInvoice(dbInvoice)
+	invoice := convertDatabaseInvoiceToInvoice(dbInvoice,
+		c.cfg.InvoiceApprovals)
 
 	err = validateUserCanSeeInvoice(invoice, user)
 	if err != nil {
 		return nil, err
 	}
 
-	var (
-		isVettedInvoice bool
-		route           string
-		requestObject   interface{}
-	)
-	if invoice.Status == v1.InvoiceStatusApproved {
-		isVettedInvoice = true
-		route = pd.GetVettedRoute
-		requestObject = pd.GetVetted{
-			Token:     id.Token,
-			Challenge: hex.EncodeToString(challenge),
-		}
-	} else {
-		isVettedInvoice = false
-		route = pd.GetUnvettedRoute
-		requestObject = pd.GetUnvetted{
-			Token:     id.Token,
-			Challenge: hex.EncodeToString(challenge),
+	if id.Version > invoice.Version {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInvoiceVersion,
 		}
 	}
 
-	responseBody, err := c.rpc(http.MethodPost, route, requestObject)
-	if err != nil {
-		return nil, err
-	}
-
-	var response string
-	var fullRecord pd.Record
-	if isVettedInvoice {
-		var pdReply pd.GetVettedReply
-		err = json.Unmarshal(responseBody, &pdReply)
-		if err != nil {
-			return nil, fmt.Errorf("Could not unmarshal "+
-				"GetVettedReply: %v", err)
-		}
-
-		response = pdReply.Response
-		fullRecord = pdReply.Record
-	} else {
-		var pdReply pd.GetUnvettedReply
-		err = json.Unmarshal(responseBody, &pdReply)
-		if err != nil {
-			return nil, fmt.Errorf("Could not unmarshal "+
-				"GetUnvettedReply: %v", err)
-		}
-
-		response = pdReply.Response
-		fullRecord = pdReply.Record
+	var version string
+	if id.Version != 0 {
+		version = strconv.FormatUint(id.Version, 10)
 	}
 
-	// Verify the challenge.
-	err = util.VerifyChallenge(c.cfg.Identity, challenge, response)
+	record, err := c.fetchVettedRecord(id.Token, version)
 	if err != nil {
 		return nil, err
 	}
 
-	invoice.File = convertInvoiceFileFromPD(fullRecord.Files)
+	if id.Version != 0 && id.Version != invoice.Version {
+		// Use the metadata of the requested version, since it may differ
+		// from the latest version stored in the database.
+		priorInvoice := convertRecordToInvoice(*record)
+		invoice.Timestamp = priorInvoice.Timestamp
+		invoice.PublicKey = priorInvoice.PublicKey
+		invoice.Signature = priorInvoice.Signature
+		invoice.Version = priorInvoice.Version
+		invoice.CensorshipRecord = priorInvoice.CensorshipRecord
+	}
+
+	invoice.File = convertInvoiceFileFromPD(record.Files)
+	invoice.Attachments = convertInvoiceAttachmentsFromPD(record.Files)
 	invoice.Username = c.getUsernameByID(invoice.UserID)
-	idr.Invoice = *invoice
-	return &idr, nil
+	return &v1.InvoiceDetailsReply{
+		Invoice: *invoice,
+	}, nil
 }
 
 // HandleSubmitInvoice handles the incoming new invoice command.
@@ -583,12 +1176,13 @@ func (c *cmswww) HandleSubmitInvoice(
 	// Assemble metdata record
 	ts := time.Now().Unix()
 	md, err := json.Marshal(BackendInvoiceMetadata{
-		Month:     ni.Month,
-		Year:      ni.Year,
-		Version:   VersionBackendInvoiceMetadata,
-		Timestamp: ts,
-		PublicKey: ni.PublicKey,
-		Signature: ni.Signature,
+		Month:          ni.Month,
+		Year:           ni.Year,
+		Version:        VersionBackendInvoiceMetadata,
+		Timestamp:      ts,
+		PublicKey:      ni.PublicKey,
+		Signature:      ni.Signature,
+		InvoiceVersion: 1,
 	})
 	if err != nil {
 		return nil, err
@@ -600,7 +1194,7 @@ func (c *cmswww) HandleSubmitInvoice(
 			ID:      mdStreamGeneral,
 			Payload: string(md),
 		}},
-		Files: convertInvoiceFileFromWWW(&ni.File),
+		Files: convertInvoiceFilesFromWWW(&ni.File, ni.Attachments),
 	}
 
 	var pdNewRecordReply pd.NewRecordReply
@@ -685,3 +1279,184 @@ func (c *cmswww) HandleSubmitInvoice(
 		pdNewRecordReply.CensorshipRecord)
 	return &nir, nil
 }
+
+// HandleEditInvoice handles the incoming edit invoice command, which submits
+// a new version of a rejected invoice under the same token and puts it back
+// into review.
+func (c *cmswww) HandleEditInvoice(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	ei := req.(*v1.EditInvoice)
+
+	// The lock is held from the status check until the database has the new
+	// version, so that the invoice can't be reviewed or revised concurrently.
+	c.Lock()
+	defer c.Unlock()
+
+	dbInvoice, err := c.db.GetInvoiceByToken(ei.Token)
+	if err != nil {
+		if err == database.ErrInvoiceNotFound {
+			return nil, v1.UserError{
+				ErrorCode: v1.ErrorStatusInvoiceNotFound,
+			}
+		}
+		return nil, err
+	}
+
+	// Only the user who submitted the invoice can revise it.
+	if dbInvoice.UserID != user.ID {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusInvoiceNotFound,
+		}
+	}
+
+	err = validateStatusTransition(dbInvoice, v1.InvoiceStatusNotReviewed)
+	if err != nil {
+		return nil, err
+	}
+
+	// The revision applies to the same month and year as the original.
+	ni := v1.SubmitInvoice{
+		Month:       dbInvoice.Month,
+		Year:        dbInvoice.Year,
+		File:        ei.File,
+		Attachments: ei.Attachments,
+		PublicKey:   ei.PublicKey,
+		Signature:   ei.Signature,
+	}
+	err = validateInvoice(&ni, user)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.fetchInvoiceFileIfNecessary(dbInvoice)
+	if err != nil {
+		return nil, err
+	}
+	merkleRoot, err := invoiceMerkleRoot(&ni.File, ni.Attachments)
+	if err != nil {
+		return nil, err
+	}
+	invoice := convertDatabaseInvoiceToInvoice(dbInvoice,
+		c.cfg.InvoiceApprovals)
+	if invoice.CensorshipRecord.Merkle == merkleRoot {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusNoInvoiceChanges,
+		}
+	}
+
+	// Files of the previous version which aren't part of the revision, such
+	// as removed attachments, are deleted from the record.
+	filesAdd := convertInvoiceFilesFromWWW(&ni.File, ni.Attachments)
+	filesDel := make([]string, 0)
+	if invoice.File != nil {
+		for _, f := range convertInvoiceFilesFromWWW(invoice.File,
+			invoice.Attachments) {
+			found := false
+			for _, newFile := range filesAdd {
+				if newFile.Name == f.Name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				filesDel = append(filesDel, f.Name)
+			}
+		}
+	}
+
+	// Assemble the new metadata record and change record.
+	ts := time.Now().Unix()
+	version := dbInvoice.Version + 1
+	md, err := json.Marshal(BackendInvoiceMetadata{
+		Month:          dbInvoice.Month,
+		Year:           dbInvoice.Year,
+		Version:        VersionBackendInvoiceMetadata,
+		Timestamp:      ts,
+		PublicKey:      ei.PublicKey,
+		Signature:      ei.Signature,
+		InvoiceVersion: version,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	changes := BackendInvoiceMDChanges{
+		Version:   VersionBackendInvoiceMDChanges,
+		Timestamp: ts,
+		NewStatus: v1.InvoiceStatusNotReviewed,
+	}
+
+	blob, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	pdCommand := pd.UpdateRecord{
+		Token:     ei.Token,
+		Challenge: hex.EncodeToString(challenge),
+		MDOverwrite: []pd.MetadataStream{
+			{
+				ID:      mdStreamGeneral,
+				Payload: string(md),
+			},
+		},
+		MDAppend: []pd.MetadataStream{
+			{
+				ID:      mdStreamChanges,
+				Payload: string(blob),
+			},
+		},
+		FilesAdd: filesAdd,
+		FilesDel: filesDel,
+	}
+
+	responseBody, err := c.rpc(http.MethodPost, pd.UpdateVettedRoute,
+		pdCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	var pdReply pd.UpdateRecordReply
+	err = json.Unmarshal(responseBody, &pdReply)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unmarshal UpdateRecordReply: %v",
+			err)
+	}
+
+	// Verify the challenge.
+	err = util.VerifyChallenge(c.cfg.Identity, challenge, pdReply.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the database with the new version of the record, which
+	// includes the updated censorship record.
+	record, err := c.fetchVettedRecord(ei.Token, "")
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.updateInventoryRecord(*record)
+	if err != nil {
+		return nil, err
+	}
+
+	dbInvoice, err = c.db.GetInvoiceByToken(ei.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.EditInvoiceReply{
+		Invoice: *convertDatabaseInvoiceToInvoice(dbInvoice,
+			c.cfg.InvoiceApprovals),
+	}, nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/database"
+)
+
+func TestPayableTotals(t *testing.T) {
+	// The line items as reviewed for the current version of the invoice.
+	lineItems := []v1.InvoiceReviewLineItem{
+		{LineNumber: 1, Hours: 10, TotalCost: 50000},
+		{LineNumber: 2, Hours: 5, TotalCost: 25000},
+		{LineNumber: 3, TotalCost: 1000},
+	}
+
+	tests := []struct {
+		name      string
+		version   uint64
+		reviews   []database.InvoiceLineItemReview
+		statuses  []v1.LineItemStatusT
+		wantHours uint64
+		wantCost  uint64
+	}{
+		{
+			name:      "never reviewed",
+			version:   1,
+			wantHours: 15,
+			wantCost:  76000,
+		},
+		{
+			name:    "reviewed",
+			version: 1,
+			reviews: []database.InvoiceLineItemReview{
+				{InvoiceVersion: 1, LineNumber: 1,
+					Status: v1.LineItemStatusApproved},
+				{InvoiceVersion: 1, LineNumber: 2,
+					Status: v1.LineItemStatusDisputed},
+			},
+			statuses: []v1.LineItemStatusT{v1.LineItemStatusApproved,
+				v1.LineItemStatusDisputed, v1.LineItemStatusUnreviewed},
+			wantHours: 10,
+			wantCost:  50000,
+		},
+		{
+			// The reviews of the previous version don't apply to the
+			// revision, which hasn't been reviewed yet.
+			name:    "revised after review",
+			version: 2,
+			reviews: []database.InvoiceLineItemReview{
+				{InvoiceVersion: 1, LineNumber: 1,
+					Status: v1.LineItemStatusDisputed},
+				{InvoiceVersion: 1, LineNumber: 2,
+					Status: v1.LineItemStatusDisputed},
+			},
+			wantHours: 15,
+			wantCost:  76000,
+		},
+		{
+			name:    "revised and reviewed again",
+			version: 2,
+			reviews: []database.InvoiceLineItemReview{
+				{InvoiceVersion: 1, LineNumber: 1,
+					Status: v1.LineItemStatusDisputed},
+				{InvoiceVersion: 2, LineNumber: 3,
+					Status: v1.LineItemStatusApproved},
+			},
+			statuses: []v1.LineItemStatusT{v1.LineItemStatusUnreviewed,
+				v1.LineItemStatusUnreviewed, v1.LineItemStatusApproved},
+			wantHours: 0,
+			wantCost:  1000,
+		},
+	}
+
+	for _, test := range tests {
+		dbInvoice := database.Invoice{
+			Version:         test.version,
+			LineItemReviews: test.reviews,
+		}
+
+		reviewed := make([]v1.InvoiceReviewLineItem, len(lineItems))
+		copy(reviewed, lineItems)
+		for idx, status := range test.statuses {
+			reviewed[idx].Status = status
+		}
+
+		hours, cost := payableTotals(&dbInvoice, reviewed)
+		if hours != test.wantHours || cost != test.wantCost {
+			t.Errorf("%v: got %v hours and cost %v, want %v hours and "+
+				"cost %v", test.name, hours, cost, test.wantHours,
+				test.wantCost)
+		}
+	}
+}
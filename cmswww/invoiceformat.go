@@ -0,0 +1,714 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/database"
+)
+
+const (
+	invoiceFilenameCSV  = "invoice.csv"
+	invoiceFilenameJSON = "invoice.json"
+
+	// Positions of the fields in v1.InvoiceFields which are validated
+	// against each other.
+	invoiceFieldType      = 0
+	invoiceFieldProposal  = 3
+	invoiceFieldHours     = 4
+	invoiceFieldTotalCost = 5
+	invoiceFieldReceipt   = 6
+	invoiceFieldKind      = 7
+	invoiceFieldMilestone = 8
+)
+
+// convertInvoiceMIMEToPD returns the filename and MIME type under which an
+// invoice file is stored in politeiad. politeiad detects the MIME type from
+// the file content, so JSON invoices are stored as plain text and are told
+// apart from CSV invoices by their filename.
+func convertInvoiceMIMEToPD(mime string) (string, string) {
+	if mime == v1.MIMETypeInvoiceJSON {
+		return invoiceFilenameJSON, v1.MIMETypeInvoiceCSV
+	}
+	return invoiceFilenameCSV, mime
+}
+
+// convertInvoiceMIMEFromPD returns the invoice MIME type of a file stored in
+// politeiad; it's the inverse of convertInvoiceMIMEToPD.
+func convertInvoiceMIMEFromPD(f *pd.File) string {
+	if f.Name == invoiceFilenameJSON {
+		return v1.MIMETypeInvoiceJSON
+	}
+	return f.MIME
+}
+
+// isInvoiceFilename returns whether a file stored in politeiad is the invoice
+// itself rather than an attachment.
+func isInvoiceFilename(name string) bool {
+	return name == invoiceFilenameCSV || name == invoiceFilenameJSON
+}
+
+// isExpenseLineItem returns whether a line item is an expense rather than
+// hours worked.
+func isExpenseLineItem(lineItemType string) bool {
+	return strings.EqualFold(strings.TrimSpace(lineItemType),
+		v1.InvoiceLineItemTypeExpense)
+}
+
+// parseLineItemKind returns the line item kind with the given name; an empty
+// name is an hourly line item.
+func parseLineItemKind(name string) (v1.LineItemKindT, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return v1.LineItemKindHourly, true
+	}
+
+	for kind, kindName := range v1.LineItemKind {
+		if kindName == name {
+			return kind, true
+		}
+	}
+	return v1.LineItemKindHourly, false
+}
+
+// invoiceFieldsMinCount returns the number of fields up to and including the
+// last required one; the optional fields after it may be omitted.
+func invoiceFieldsMinCount() int {
+	count := 0
+	for idx, field := range v1.InvoiceFields {
+		if field.Required {
+			count = idx + 1
+		}
+	}
+	return count
+}
+
+// invoiceFieldValue returns the trimmed value of a field in a CSV record, or
+// an empty string if it was omitted.
+func invoiceFieldValue(record []string, idx int) string {
+	if idx >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[idx])
+}
+
+// normalizeWorkType returns the key under which hourly rates for a type of
+// work are stored, so that rates match regardless of case and spacing.
+func normalizeWorkType(workType string) string {
+	return strings.ToLower(strings.TrimSpace(workType))
+}
+
+// contractorHourlyRate returns the contractor's hourly rate for the given type
+// of work, falling back to their default rate. It returns 0 if no rate is set.
+// Hourly rates are in the base currency, so they don't apply to invoices
+// billed in other currencies.
+func contractorHourlyRate(user *database.User, workType, currency string) uint64 {
+	if currency != v1.PolicyInvoiceCurrency {
+		return 0
+	}
+	if rate, ok := user.WorkTypeHourlyRates[normalizeWorkType(workType)]; ok {
+		return rate
+	}
+	return user.HourlyRate
+}
+
+// mulAmount returns the product of two amounts, and false if it overflows.
+func mulAmount(a, b uint64) (uint64, bool) {
+	if a != 0 && b > math.MaxUint64/a {
+		return 0, false
+	}
+	return a * b, true
+}
+
+// addAmount returns the sum of two amounts, and false if it overflows.
+func addAmount(a, b uint64) (uint64, bool) {
+	if b > math.MaxUint64-a {
+		return 0, false
+	}
+	return a + b, true
+}
+
+// invoiceCostTotal is the total cost of an invoice being validated. It's
+// summed with overflow checks, so that large costs can't wrap around into a
+// small amount.
+type invoiceCostTotal struct {
+	total uint64
+}
+
+// add adds the cost of a line item to the total, which may not exceed
+// v1.PolicyMaxInvoiceAmount.
+func (t *invoiceCostTotal) add(cost uint64) error {
+	total, ok := addAmount(t.total, cost)
+	if !ok || total > v1.PolicyMaxInvoiceAmount {
+		return fmt.Errorf("total cost of the invoice exceeds %v",
+			v1.PolicyMaxInvoiceAmount)
+	}
+	t.total = total
+	return nil
+}
+
+// validateLineItemHours verifies that the hours billed in a line item don't
+// exceed v1.PolicyMaxLineItemHours.
+func validateLineItemHours(hours uint64) error {
+	if hours > v1.PolicyMaxLineItemHours {
+		return fmt.Errorf("hours must be at most %v",
+			v1.PolicyMaxLineItemHours)
+	}
+	return nil
+}
+
+// hourlyLineItemCost returns the cost of an hourly line item, after verifying
+// that its rate is within bounds.
+func hourlyLineItemCost(hours, rate uint64) (uint64, error) {
+	if rate == 0 || rate > v1.PolicyMaxInvoiceAmount {
+		return 0, fmt.Errorf("rate must be a positive number of at most %v",
+			v1.PolicyMaxInvoiceAmount)
+	}
+	cost, ok := mulAmount(hours, rate)
+	if !ok {
+		return 0, fmt.Errorf("cost is too large")
+	}
+	return cost, nil
+}
+
+func invalidInvoiceInputError(context ...string) error {
+	return v1.UserError{
+		ErrorCode:    v1.ErrorStatusInvalidInput,
+		ErrorContext: context,
+	}
+}
+
+// parseCurrencyComment returns the currency set by a line of a CSV invoice,
+// and whether the line is a currency comment at all.
+func parseCurrencyComment(line string) (string, bool) {
+	comment := strings.TrimSpace(strings.TrimPrefix(line,
+		string(v1.PolicyInvoiceCommentChar)))
+	if !strings.HasPrefix(line, string(v1.PolicyInvoiceCommentChar)) ||
+		!strings.HasPrefix(strings.ToLower(comment),
+			v1.PolicyInvoiceCurrencyTag) {
+		return "", false
+	}
+
+	return strings.TrimSpace(comment[len(v1.PolicyInvoiceCurrencyTag):]), true
+}
+
+// decodeInvoiceInput decodes a JSON invoice, rejecting unknown fields so that
+// typos don't silently drop data.
+func decodeInvoiceInput(data []byte) (*v1.InvoiceInput, error) {
+	var ii v1.InvoiceInput
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&ii); err != nil {
+		return nil, invalidInvoiceInputError(
+			fmt.Sprintf("invalid JSON invoice: %v", err))
+	}
+
+	return &ii, nil
+}
+
+// validateInvoiceInput verifies that a JSON invoice is complete, that it
+// applies to the given month and year and that the receipts it references are
+// attached.
+func validateInvoiceInput(ii *v1.InvoiceInput, month, year uint16, receipts map[string]bool) error {
+	if ii.Version == 0 || ii.Version > v1.InvoiceFormatVersion {
+		return invalidInvoiceInputError(
+			fmt.Sprintf("unsupported invoice format version %v", ii.Version))
+	}
+
+	if ii.Month != month || ii.Year != year {
+		return invalidInvoiceInputError(
+			fmt.Sprintf("invoice is for %02v/%v, expected %02v/%v",
+				ii.Month, ii.Year, month, year))
+	}
+
+	if _, err := parseCurrency(ii.Currency); err != nil {
+		return err
+	}
+
+	if len(ii.LineItems) == 0 && len(ii.Expenses) == 0 {
+		return invalidInvoiceInputError("invoice has no line items")
+	}
+
+	var totalCost invoiceCostTotal
+	for idx, lineItem := range ii.LineItems {
+		context := fmt.Sprintf("line item %v", idx+1)
+		if strings.TrimSpace(lineItem.Type) == "" {
+			return invalidInvoiceInputError(context, "type is required")
+		}
+		if strings.TrimSpace(lineItem.Description) == "" {
+			return invalidInvoiceInputError(context,
+				"description is required")
+		}
+
+		kind, ok := parseLineItemKind(lineItem.Kind)
+		if !ok {
+			return invalidInvoiceInputError(context,
+				fmt.Sprintf("unknown line item kind %v", lineItem.Kind))
+		}
+		err := validateLineItemKind(kind, lineItem.Proposal,
+			lineItem.Milestone)
+		if err != nil {
+			return invalidInvoiceInputError(context, err.Error())
+		}
+		err = validateLineItemHours(lineItem.Hours)
+		if err != nil {
+			return invalidInvoiceInputError(context, err.Error())
+		}
+
+		if kind != v1.LineItemKindHourly {
+			if lineItem.Amount == 0 {
+				return invalidInvoiceInputError(context,
+					"amount must be a positive number")
+			}
+			err = totalCost.add(lineItem.Amount)
+			if err != nil {
+				return invalidInvoiceInputError(context, err.Error())
+			}
+			continue
+		}
+
+		if lineItem.Hours == 0 {
+			return invalidInvoiceInputError(context,
+				"hours must be a positive number")
+		}
+		cost, err := hourlyLineItemCost(lineItem.Hours, lineItem.Rate)
+		if err != nil {
+			return invalidInvoiceInputError(context, err.Error())
+		}
+		err = totalCost.add(cost)
+		if err != nil {
+			return invalidInvoiceInputError(context, err.Error())
+		}
+	}
+
+	for idx, expense := range ii.Expenses {
+		context := fmt.Sprintf("expense %v", idx+1)
+		if strings.TrimSpace(expense.Description) == "" {
+			return invalidInvoiceInputError(context,
+				"description is required")
+		}
+		if expense.Amount == 0 {
+			return invalidInvoiceInputError(context,
+				"amount must be a positive number")
+		}
+		if expense.Receipt != "" && !receipts[expense.Receipt] {
+			return invalidInvoiceInputError(context,
+				fmt.Sprintf("receipt %v is not attached", expense.Receipt))
+		}
+		err := totalCost.add(expense.Amount)
+		if err != nil {
+			return invalidInvoiceInputError(context, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// validateInvoiceCSV verifies that a CSV invoice can be parsed according to
+// v1.InvoiceFields and that its month header matches the given month and
+// year. Errors point at the line, or the line item and field, that failed
+// validation.
+//
+// The total cost of a line item may be omitted if the contractor has an
+// hourly rate for its type of work, in which case it's computed from it.
+// Expenses must have a total cost and no hours, and the receipts they
+// reference must be attached. Invoices are billed in the base currency unless
+// a currency comment comes before the line items. Hours and the total cost of
+// the invoice are bounded the same way as in JSON invoices.
+func validateInvoiceCSV(data []byte, month, year uint16, user *database.User, receipts map[string]bool) error {
+	comment := string(v1.PolicyInvoiceCommentChar)
+	currency := v1.PolicyInvoiceCurrency
+	headerFound := false
+
+	// The month header must be the first line which isn't empty, and the
+	// currency must be set before the first line item.
+	err := scanInvoiceCSVComments(data, func(lineNum int, line string, afterLineItem bool) error {
+		lineContext := fmt.Sprintf("line %v", lineNum)
+		if !headerFound {
+			if afterLineItem {
+				return invalidInvoiceInputError("line 1",
+					"missing month header")
+			}
+
+			t, err := time.Parse(comment+" 2006-01", strings.TrimSpace(line))
+			if err != nil {
+				return invalidInvoiceInputError(lineContext, fmt.Sprintf(
+					"first line must be a comment with the month and year "+
+						"in the format %v YYYY-MM", comment))
+			}
+			if uint16(t.Month()) != month || uint16(t.Year()) != year {
+				return invalidInvoiceInputError(lineContext, fmt.Sprintf(
+					"invoice is for %v, expected %04v-%02v",
+					t.Format("2006-01"), year, month))
+			}
+
+			headerFound = true
+			return nil
+		}
+
+		value, ok := parseCurrencyComment(line)
+		if !ok {
+			return nil
+		}
+		if afterLineItem {
+			return invalidInvoiceInputError(lineContext,
+				"the currency must be set before the first line item")
+		}
+
+		var err error
+		currency, err = parseCurrency(value)
+		if err != nil {
+			return invalidInvoiceInputError(lineContext,
+				fmt.Sprintf("unsupported currency %v", value))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !headerFound {
+		return invalidInvoiceInputError("line 1", "missing month header")
+	}
+
+	// The line items are read the same way as they're parsed, so that the
+	// validated records are the ones which end up in the invoice.
+	csvReader := newInvoiceCSVReader(data)
+	lineItemCount := 0
+	var totalCost invoiceCostTotal
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return invalidInvoiceInputError(err.Error())
+		}
+
+		lineItemCount++
+		lineItemContext := fmt.Sprintf("line item %v", lineItemCount)
+
+		if len(record) < invoiceFieldsMinCount() ||
+			len(record) > len(v1.InvoiceFields) {
+			return invalidInvoiceInputError(lineItemContext, fmt.Sprintf(
+				"expected between %v and %v fields, found %v",
+				invoiceFieldsMinCount(), len(v1.InvoiceFields), len(record)))
+		}
+
+		for idx, field := range v1.InvoiceFields {
+			value := invoiceFieldValue(record, idx)
+			fieldContext := fmt.Sprintf("field %v (%v)", idx+1, field.Name)
+			if field.Required && value == "" {
+				return invalidInvoiceInputError(lineItemContext,
+					fieldContext, "field is required")
+			}
+
+			if field.Type == v1.InvoiceFieldTypeUint && value != "" {
+				_, err := strconv.ParseUint(value, 10, 64)
+				if err != nil {
+					return invalidInvoiceInputError(lineItemContext,
+						fieldContext, "field must be a whole number")
+				}
+			}
+		}
+
+		cost, err := validateInvoiceCSVLineItem(record, user, currency,
+			receipts)
+		if err != nil {
+			return invalidInvoiceInputError(lineItemContext, err.Error())
+		}
+		err = totalCost.add(cost)
+		if err != nil {
+			return invalidInvoiceInputError(lineItemContext, err.Error())
+		}
+	}
+
+	if lineItemCount == 0 {
+		return invalidInvoiceInputError("invoice has no line items")
+	}
+
+	return nil
+}
+
+// newInvoiceCSVReader returns a reader of the line items of a CSV invoice,
+// which skips the comments.
+func newInvoiceCSVReader(data []byte) *csv.Reader {
+	csvReader := csv.NewReader(bytes.NewReader(data))
+	csvReader.Comma = v1.PolicyInvoiceFieldDelimiterChar
+	csvReader.Comment = v1.PolicyInvoiceCommentChar
+	csvReader.TrimLeadingSpace = true
+	csvReader.FieldsPerRecord = -1
+	return csvReader
+}
+
+// scanInvoiceCSVComments calls fn with each comment of a CSV invoice, along
+// with its line number and whether a line item comes before it. Comments
+// start with the comment character in the first column, like the ones
+// skipped by the CSV reader; lines which start with spaces are line items.
+func scanInvoiceCSVComments(data []byte, fn func(lineNum int, line string, afterLineItem bool) error) error {
+	comment := string(v1.PolicyInvoiceCommentChar)
+	afterLineItem := false
+	for idx, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, comment) {
+			afterLineItem = true
+			continue
+		}
+
+		err := fn(idx+1, line, afterLineItem)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateLineItemKind verifies that only milestone line items reference a
+// milestone, and that they reference the proposal the milestone belongs to.
+func validateLineItemKind(kind v1.LineItemKindT, proposal, milestone string) error {
+	if kind != v1.LineItemKindMilestone {
+		if strings.TrimSpace(milestone) != "" {
+			return fmt.Errorf("only milestone line items can reference a " +
+				"milestone")
+		}
+		return nil
+	}
+
+	if strings.TrimSpace(proposal) == "" {
+		return fmt.Errorf("milestone line items must reference a proposal")
+	}
+	if strings.TrimSpace(milestone) == "" {
+		return fmt.Errorf("milestone line items must reference a milestone")
+	}
+	return nil
+}
+
+// validateInvoiceCSVLineItem verifies the fields of a line item which depend
+// on each other or on the rest of the invoice, and returns the cost of the
+// line item.
+func validateInvoiceCSVLineItem(record []string, user *database.User, currency string, receipts map[string]bool) (uint64, error) {
+	lineItemType := invoiceFieldValue(record, invoiceFieldType)
+	totalCost := invoiceFieldValue(record, invoiceFieldTotalCost)
+	receipt := invoiceFieldValue(record, invoiceFieldReceipt)
+
+	// The fields have already been verified to be whole numbers.
+	hours, _ := strconv.ParseUint(invoiceFieldValue(record,
+		invoiceFieldHours), 10, 64)
+	cost, _ := strconv.ParseUint(totalCost, 10, 64)
+
+	kindName := invoiceFieldValue(record, invoiceFieldKind)
+	kind, ok := parseLineItemKind(kindName)
+	if !ok {
+		return 0, fmt.Errorf("unknown line item kind %v", kindName)
+	}
+	err := validateLineItemKind(kind,
+		invoiceFieldValue(record, invoiceFieldProposal),
+		invoiceFieldValue(record, invoiceFieldMilestone))
+	if err != nil {
+		return 0, err
+	}
+
+	if isExpenseLineItem(lineItemType) {
+		if hours != 0 {
+			return 0, fmt.Errorf("expenses must have 0 hours")
+		}
+		if totalCost == "" {
+			return 0, fmt.Errorf("expenses must have a total cost")
+		}
+		if receipt != "" && !receipts[receipt] {
+			return 0, fmt.Errorf("receipt %v is not attached", receipt)
+		}
+		return cost, nil
+	}
+
+	if receipt != "" {
+		return 0, fmt.Errorf("only expenses can reference a receipt")
+	}
+
+	err = validateLineItemHours(hours)
+	if err != nil {
+		return 0, err
+	}
+
+	// Fixed and milestone line items are billed by their total cost, and
+	// may have no hours.
+	if kind != v1.LineItemKindHourly {
+		if totalCost == "" {
+			return 0, fmt.Errorf("%v line items must have a total cost",
+				v1.LineItemKind[kind])
+		}
+		return cost, nil
+	}
+
+	if hours == 0 {
+		return 0, fmt.Errorf("hourly line items must have hours")
+	}
+	if totalCost != "" {
+		return cost, nil
+	}
+
+	// The total cost is computed from the contractor's rate when it's left
+	// out.
+	rate := contractorHourlyRate(user, lineItemType, currency)
+	if rate == 0 {
+		return 0, fmt.Errorf("total cost is required since there is no " +
+			"hourly rate set for this type of work")
+	}
+	return hourlyLineItemCost(hours, rate)
+}
+
+// parseInvoice converts an invoice file, in either the CSV or the JSON format,
+// into its line items and the currency in which it's billed.
+func parseInvoice(file *database.File) ([]v1.InvoiceReviewLineItem, string, error) {
+	data, err := base64.StdEncoding.DecodeString(file.Payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if file.MIME == v1.MIMETypeInvoiceJSON {
+		return parseInvoiceJSON(data)
+	}
+	return parseInvoiceCSV(data)
+}
+
+func parseInvoiceCSV(data []byte) ([]v1.InvoiceReviewLineItem, string, error) {
+	// The currency comment is skipped by the CSV reader along with the
+	// other comments, so it's looked up beforehand.
+	currency := v1.PolicyInvoiceCurrency
+	err := scanInvoiceCSVComments(data, func(lineNum int, line string, afterLineItem bool) error {
+		value, ok := parseCurrencyComment(line)
+		if !ok || afterLineItem {
+			return nil
+		}
+
+		var err error
+		currency, err = parseCurrency(value)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	records, err := newInvoiceCSVReader(data).ReadAll()
+	if err != nil {
+		return nil, "", err
+	}
+
+	lineItems := make([]v1.InvoiceReviewLineItem, 0, len(records))
+	for _, record := range records {
+		lineItem := v1.InvoiceReviewLineItem{}
+		for idx := range record {
+			var err error
+			switch idx {
+			case 0:
+				lineItem.Type = record[idx]
+			case 1:
+				lineItem.Subtype = record[idx]
+			case 2:
+				lineItem.Description = record[idx]
+			case 3:
+				lineItem.Proposal = record[idx]
+			case 4:
+				lineItem.Hours, err = strconv.ParseUint(
+					strings.TrimSpace(record[idx]), 10, 64)
+				if err != nil {
+					return nil, "", err
+				}
+			case 5:
+				// The total cost is optional; it's computed from the
+				// contractor's hourly rate when omitted.
+				totalCost := strings.TrimSpace(record[idx])
+				if totalCost == "" {
+					continue
+				}
+
+				lineItem.TotalCost, err = strconv.ParseUint(totalCost, 10, 64)
+				if err != nil {
+					return nil, "", err
+				}
+			case 6:
+				lineItem.Receipt = strings.TrimSpace(record[idx])
+			case 7:
+				var ok bool
+				lineItem.Kind, ok = parseLineItemKind(record[idx])
+				if !ok {
+					return nil, "", fmt.Errorf("unknown line item kind %v",
+						record[idx])
+				}
+			case 8:
+				lineItem.Milestone = strings.TrimSpace(record[idx])
+			}
+		}
+
+		lineItems = append(lineItems, lineItem)
+	}
+
+	return lineItems, currency, nil
+}
+
+func parseInvoiceJSON(data []byte) ([]v1.InvoiceReviewLineItem, string, error) {
+	ii, err := decodeInvoiceInput(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	lineItems := make([]v1.InvoiceReviewLineItem, 0,
+		len(ii.LineItems)+len(ii.Expenses))
+	for _, v := range ii.LineItems {
+		kind, ok := parseLineItemKind(v.Kind)
+		if !ok {
+			return nil, "", fmt.Errorf("unknown line item kind %v", v.Kind)
+		}
+
+		totalCost := v.Amount
+		if kind == v1.LineItemKindHourly {
+			totalCost, ok = mulAmount(v.Hours, v.Rate)
+			if !ok {
+				return nil, "", invalidInvoiceInputError(
+					fmt.Sprintf("cost of %v hours at %v overflows",
+						v.Hours, v.Rate))
+			}
+		}
+
+		lineItems = append(lineItems, v1.InvoiceReviewLineItem{
+			Kind:        kind,
+			Type:        v.Type,
+			Subtype:     v.Subtype,
+			Description: v.Description,
+			Proposal:    v.Proposal,
+			Hours:       v.Hours,
+			TotalCost:   totalCost,
+			Milestone:   v.Milestone,
+		})
+	}
+	for _, v := range ii.Expenses {
+		lineItems = append(lineItems, v1.InvoiceReviewLineItem{
+			Type:        v1.InvoiceLineItemTypeExpense,
+			Description: v.Description,
+			Proposal:    v.Proposal,
+			TotalCost:   v.Amount,
+			Receipt:     v.Receipt,
+		})
+	}
+
+	currency, err := parseCurrency(ii.Currency)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return lineItems, currency, nil
+}
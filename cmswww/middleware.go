@@ -36,23 +36,44 @@ func (c *cmswww) isLoggedIn(f http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// isLoggedInAsAdmin ensures that a user is logged in as an admin user
-// before calling the next function.
-func (c *cmswww) isLoggedInAsAdmin(f http.HandlerFunc) http.HandlerFunc {
+// hasPermission ensures that a user is logged in as an admin user, or as a
+// user with a role which grants the permission, before calling the next
+// function. If the server requires it, the user must also have enabled
+// two-factor authentication.
+func (c *cmswww) hasPermission(perm permission, f http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Check if user is admin
-		isAdmin, err := c.isAdmin(r)
-		log.Debugf("isLoggedInAsAdmin: %v %v %v %v %v", isAdmin, remoteAddr(r),
-			r.Method, r.URL, r.Proto)
+		// Check if user has the permission
+		user, err := c.GetSessionUser(r)
+		allowed := userHasPermission(user, perm)
+		log.Debugf("hasPermission: %v %v %v %v %v %v", perm, allowed,
+			remoteAddr(r), r.Method, r.URL, r.Proto)
 		if err != nil {
-			log.Errorf("isLoggedInAsAdmin: isAdmin %v", err)
+			log.Errorf("hasPermission: GetSessionUser %v", err)
 			util.RespondWithJSON(w, http.StatusUnauthorized, v1.ErrorReply{
 				ErrorCode: int64(v1.ErrorStatusNotLoggedIn),
 			})
 			return
 		}
-		if !isAdmin {
-			util.RespondWithJSON(w, http.StatusForbidden, v1.ErrorReply{})
+		if user == nil {
+			util.RespondWithJSON(w, http.StatusUnauthorized, v1.ErrorReply{
+				ErrorCode: int64(v1.ErrorStatusNotLoggedIn),
+			})
+			return
+		}
+		if !allowed {
+			util.RespondWithJSON(w, http.StatusForbidden, v1.ErrorReply{
+				ErrorCode: int64(v1.ErrorStatusMissingUserRole),
+			})
+			return
+		}
+
+		// Privileged users who haven't enabled two-factor authentication
+		// can still use the routes which only require being logged in, so
+		// that they can enroll.
+		if c.cfg.AdminTOTP && !user.TOTPVerified {
+			util.RespondWithJSON(w, http.StatusForbidden, v1.ErrorReply{
+				ErrorCode: int64(v1.ErrorStatusTOTPEnrollmentRequired),
+			})
 			return
 		}
 
@@ -20,6 +20,7 @@ var activeNetParams = &mainNetParams
 type params struct {
 	*chaincfg.Params
 	WalletRPCServerPort string
+	DcrdRPCServerPort   string
 }
 
 // mainNetParams contains parameters specific to the main network
@@ -31,6 +32,7 @@ type params struct {
 var mainNetParams = params{
 	Params:              &chaincfg.MainNetParams,
 	WalletRPCServerPort: netparams.MainNetParams.GRPCServerPort,
+	DcrdRPCServerPort:   netparams.MainNetParams.JSONRPCClientPort,
 }
 
 // testNet3Params contains parameters specific to the test network (version 0)
@@ -40,6 +42,7 @@ var mainNetParams = params{
 var testNet3Params = params{
 	Params:              &chaincfg.TestNet3Params,
 	WalletRPCServerPort: netparams.TestNet3Params.GRPCServerPort,
+	DcrdRPCServerPort:   netparams.TestNet3Params.JSONRPCClientPort,
 }
 
 // simNetParams contains parameters specific to the simulation test network
@@ -47,6 +50,7 @@ var testNet3Params = params{
 var simNetParams = params{
 	Params:              &chaincfg.SimNetParams,
 	WalletRPCServerPort: netparams.SimNetParams.GRPCServerPort,
+	DcrdRPCServerPort:   netparams.SimNetParams.JSONRPCClientPort,
 }
 
 // netName returns the name used when referring to a decred network.  At the
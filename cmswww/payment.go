@@ -2,11 +2,15 @@ package main
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/decred/dcrd/dcrutil"
 	"github.com/decred/politeia/util"
 
 	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/chain"
 	"github.com/decred/contractor-mgmt/cmswww/database"
 )
 
@@ -22,9 +26,9 @@ const (
 	// for transactions.
 	pollExpiryDuration = time.Hour * 24
 
-	// pollCheckGap is the amount of time the server sleeps after polling for
-	// a payment address.
-	pollCheckGap = time.Second * 5
+	// pollCheckGap is the amount of time the server sleeps after polling all
+	// of the payment addresses.
+	pollCheckGap = time.Second * 30
 )
 
 func pollHasExpired(pollExpiry int64) bool {
@@ -101,92 +105,259 @@ func (c *cmswww) createPolledPaymentsCopy() map[string]polledPayment {
 	return copy
 }
 
-func (c *cmswww) checkForInvoicePayments(polledPayments map[string]polledPayment) (bool, []string) {
-	var tokensToRemove []string
+// pollResult is the outcome of checking the payment of a single invoice.
+type pollResult int
 
-	for token, polledPayment := range polledPayments {
-		invoice, err := c.db.GetInvoiceByToken(token)
-		if err != nil {
-			if err == database.ErrShutdown {
-				// The database is shutdown, so stop the thread.
-				return false, nil
-			}
+const (
+	pollKeep     pollResult = iota // Keep polling the invoice
+	pollRemove                     // Stop polling the invoice
+	pollShutdown                   // The database is shutdown
+)
 
-			log.Errorf("cannot fetch invoice by token %v: %v\n", token, err)
-			continue
+// checkForInvoicePayment checks whether the payment of a single invoice has
+// been made.
+func (c *cmswww) checkForInvoicePayment(token string, polledPayment polledPayment) pollResult {
+	invoice, err := c.db.GetInvoiceByToken(token)
+	if err != nil {
+		if err == database.ErrShutdown {
+			return pollShutdown
 		}
 
-		log.Tracef("Checking the payment address for invoice %v...",
-			token)
+		log.Errorf("cannot fetch invoice by token %v: %v\n", token, err)
+		return pollKeep
+	}
+
+	log.Tracef("Checking the payment address for invoice %v...",
+		token)
 
-		if invoice.Status == v1.InvoiceStatusPaid {
-			// The invoice could have been marked as paid by some external
-			// mechanism, so just remove him from polling.
-			tokensToRemove = append(tokensToRemove, token)
-			log.Tracef("  removing from polling, invoice already paid")
-			continue
+	if invoice.Status == v1.InvoiceStatusPaid {
+		// The invoice could have been marked as paid by some external
+		// mechanism, so just remove him from polling.
+		log.Tracef("  removing %v from polling, invoice already paid", token)
+		return pollRemove
+	}
+
+	if pollHasExpired(polledPayment.pollExpiry) {
+		log.Tracef("  removing %v from polling, poll has expired", token)
+		return pollRemove
+	}
+
+	txs, err := c.watcher.FetchTxs(polledPayment.address,
+		polledPayment.txNotBefore)
+	if err != nil {
+		log.Errorf("cannot fetch txs: %v\n", err)
+		return pollKeep
+	}
+
+	// Partially paid invoices are still polled, since the rest of the
+	// payment may be sent in another tx.
+	paid, err := c.updateInvoicePayment(token, polledPayment.address, txs)
+	if err != nil {
+		if err == database.ErrShutdown {
+			return pollShutdown
 		}
 
-		if pollHasExpired(polledPayment.pollExpiry) {
-			tokensToRemove = append(tokensToRemove, invoice.Token)
-			log.Tracef("  removing from polling, poll has expired")
-			continue
+		log.Errorf("cannot update invoice with token %v: %v",
+			invoice.Token, err)
+		return pollKeep
+	}
+	if !paid {
+		return pollKeep
+	}
+
+	log.Tracef("  removing %v from polling, invoice just paid", token)
+	return pollRemove
+}
+
+// checkForInvoicePayments checks the given payments concurrently, using at
+// most the configured number of workers. It returns false if the poller
+// should stop, along with the payments which no longer need to be polled.
+func (c *cmswww) checkForInvoicePayments(polledPayments map[string]polledPayment) (bool, map[string]polledPayment) {
+	var (
+		wg               sync.WaitGroup
+		mtx              sync.Mutex
+		paymentsToRemove = make(map[string]polledPayment)
+		shutdown         bool
+	)
+
+	tokens := make(chan string)
+	for i := 0; i < c.cfg.PaymentPollWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for token := range tokens {
+				result := c.checkForInvoicePayment(token,
+					polledPayments[token])
+
+				mtx.Lock()
+				switch result {
+				case pollRemove:
+					paymentsToRemove[token] = polledPayments[token]
+				case pollShutdown:
+					shutdown = true
+				}
+				mtx.Unlock()
+			}
+		}()
+	}
+
+	// Stop handing out invoices as soon as the server is shutting down.
+	quit := false
+dispatch:
+	for token := range polledPayments {
+		select {
+		case tokens <- token:
+		case <-c.pollQuit:
+			quit = true
+			break dispatch
 		}
+	}
+	close(tokens)
+	wg.Wait()
 
-		tx, _, err := util.FetchTxWithBlockExplorers(polledPayment.address,
-			polledPayment.amount, polledPayment.txNotBefore,
-			c.cfg.MinConfirmationsRequired)
-		if err != nil {
-			log.Errorf("cannot fetch tx: %v\n", err)
-			continue
+	return !quit && !shutdown, paymentsToRemove
+}
+
+// isPaymentComplete returns whether enough funds have been received for a
+// payment, allowing for the configured tolerance.
+func (c *cmswww) isPaymentComplete(payment *database.InvoicePayment) bool {
+	tolerance := uint64(float64(payment.Amount) * c.cfg.PaymentTolerance)
+	return payment.AmountReceived+tolerance >= payment.Amount
+}
+
+// updateInvoicePayment stores the confirmed txs which were sent to the
+// payment address of an invoice, and marks the invoice as paid or partially
+// paid depending on the total amount received. It returns whether the
+// invoice has been paid.
+//
+// This function must be called WITHOUT the mutex held.
+func (c *cmswww) updateInvoicePayment(token, address string, txs []chain.Tx) (bool, error) {
+	// The lock is held and the invoice is read again, since admins may have
+	// changed it while the txs were being fetched.
+	c.Lock()
+	defer c.Unlock()
+
+	invoice, err := c.db.GetInvoiceByToken(token)
+	if err != nil {
+		return false, err
+	}
+
+	// Only invoices which are waiting for their payment are updated, so
+	// that a reconciliation or a status change made by an admin is kept.
+	switch invoice.Status {
+	case v1.InvoiceStatusApproved, v1.InvoiceStatusPartiallyPaid:
+	default:
+		return invoice.Status == v1.InvoiceStatusPaid, nil
+	}
+
+	// The most recent payment to the address is the one being polled.
+	var payment *database.InvoicePayment
+	for idx := len(invoice.Payments) - 1; idx >= 0; idx-- {
+		if invoice.Payments[idx].Address == address {
+			payment = &invoice.Payments[idx]
+			break
 		}
+	}
+	if payment == nil {
+		return false, fmt.Errorf("no payment to address %v", address)
+	}
 
-		if tx != "" {
-			// Update the invoice in the database.
-			invoice.Status = v1.InvoiceStatusPaid
-			// TOOD: add invoice payments table
-			err = c.db.UpdateInvoice(invoice)
-			if err != nil {
-				if err == database.ErrShutdown {
-					// The database is shutdown, so stop the thread.
-					return false, nil
-				}
+	var (
+		txIDs         []string
+		received      uint64
+		confirmations uint64
+	)
+	for _, tx := range txs {
+		if tx.Confirmations < c.cfg.MinConfirmationsRequired {
+			continue
+		}
 
-				log.Errorf("cannot update invoice with token %v: %v",
-					invoice.Token, err)
-				continue
-			}
+		if len(txIDs) == 0 || tx.Confirmations < confirmations {
+			confirmations = tx.Confirmations
+		}
+		txIDs = append(txIDs, tx.TxID)
+		received += tx.Amount
+	}
+	if received == 0 || received == payment.AmountReceived {
+		return invoice.Status == v1.InvoiceStatusPaid, nil
+	}
 
-			// Remove this invoice from polling.
-			tokensToRemove = append(tokensToRemove, token)
-			log.Tracef("  removing from polling, invoice just paid")
+	payment.TxIDs = txIDs
+	payment.AmountReceived = received
+	payment.Confirmations = confirmations
+	payment.DetectedAt = time.Now().Unix()
+
+	var (
+		newStatus v1.InvoiceStatusT
+		reason    string
+	)
+	txList := strings.Join(txIDs, ", ")
+	if c.isPaymentComplete(payment) {
+		newStatus = v1.InvoiceStatusPaid
+		reason = fmt.Sprintf("payment of %v detected in txs %v",
+			dcrutil.Amount(received), txList)
+		if received > payment.Amount {
+			reason += fmt.Sprintf("; overpaid by %v",
+				dcrutil.Amount(received-payment.Amount))
+		} else if received < payment.Amount {
+			reason += fmt.Sprintf("; underpaid by %v within tolerance",
+				dcrutil.Amount(payment.Amount-received))
 		}
+	} else {
+		newStatus = v1.InvoiceStatusPartiallyPaid
+		reason = fmt.Sprintf("partial payment of %v out of %v detected "+
+			"in txs %v", dcrutil.Amount(received),
+			dcrutil.Amount(payment.Amount), txList)
+	}
 
-		time.Sleep(pollCheckGap)
+	if newStatus == invoice.Status {
+		// A further partial payment only updates the amount received.
+		return false, c.db.UpdateInvoice(invoice)
 	}
 
-	return true, tokensToRemove
+	// The status change is recorded in politeiad so that it isn't lost when
+	// the inventory is reloaded.
+	err = c.setInvoiceStatus(invoice, "", newStatus, reason)
+	if err != nil {
+		return false, err
+	}
+
+	return newStatus == v1.InvoiceStatusPaid, nil
 }
 
-func (c *cmswww) removeInvoicesFromPolling(tokensToRemove []string) {
+// removeInvoicesFromPolling stops polling the given payments. An invoice is
+// kept if its payment was replaced by a new quote while it was being checked.
+func (c *cmswww) removeInvoicesFromPolling(paymentsToRemove map[string]polledPayment) {
 	c.Lock()
 	defer c.Unlock()
 
-	for _, token := range tokensToRemove {
-		delete(c.polledPayments, token)
+	for token, removed := range paymentsToRemove {
+		polledPayment, ok := c.polledPayments[token]
+		if ok && polledPayment.address == removed.address {
+			delete(c.polledPayments, token)
+		}
 	}
 }
 
 func (c *cmswww) checkForPayments() {
+	defer c.pollWG.Done()
+
 	for {
 		invoicePaymentsToCheck := c.createPolledPaymentsCopy()
-		shouldContinue, invoiceTokensToRemove := c.checkForInvoicePayments(invoicePaymentsToCheck)
+		shouldContinue, invoicePaymentsToRemove := c.checkForInvoicePayments(invoicePaymentsToCheck)
+		c.removeInvoicesFromPolling(invoicePaymentsToRemove)
 		if !shouldContinue {
+			log.Infof("Payment checker stopped")
 			return
 		}
-		c.removeInvoicesFromPolling(invoiceTokensToRemove)
 
-		time.Sleep(pollCheckGap)
+		select {
+		case <-time.After(pollCheckGap):
+		case <-c.pollQuit:
+			log.Infof("Payment checker stopped")
+			return
+		}
 	}
 }
 
@@ -197,6 +368,14 @@ func (c *cmswww) initPaymentChecker() error {
 	}
 
 	// Start the thread that checks for payments.
+	c.pollWG.Add(1)
 	go c.checkForPayments()
 	return nil
 }
+
+// stopPaymentChecker signals the payment checker to stop and waits for the
+// payments which are being checked to finish.
+func (c *cmswww) stopPaymentChecker() {
+	close(c.pollQuit)
+	c.pollWG.Wait()
+}
@@ -0,0 +1,311 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pd "github.com/decred/politeia/politeiad/api/v1"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/slog"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/chain"
+	"github.com/decred/contractor-mgmt/cmswww/chain/memory"
+	"github.com/decred/contractor-mgmt/cmswww/database"
+)
+
+const (
+	testInvoiceToken   = "d2ae05a1b4bfaf4b0b4d8b85a5f5bbc8b1e1b6b2c0d4f41c1a9b9a6d0a9e8f01"
+	testPaymentAddress = "TsfDLrRkk9ciUuwfp2b8PawwnukYD7yAjGd"
+	testPaymentAmount  = 100000000 // 1 DCR
+)
+
+// testDatabase is an in-memory database which only implements the invoice
+// functions used by the payment checker.
+type testDatabase struct {
+	database.Database
+	sync.Mutex
+
+	invoices map[string]database.Invoice
+}
+
+func copyInvoice(invoice database.Invoice) database.Invoice {
+	invoice.Changes = append([]database.InvoiceChange(nil),
+		invoice.Changes...)
+	invoice.Payments = append([]database.InvoicePayment(nil),
+		invoice.Payments...)
+	return invoice
+}
+
+func (db *testDatabase) GetInvoiceByToken(token string) (*database.Invoice, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	invoice, ok := db.invoices[token]
+	if !ok {
+		return nil, database.ErrInvoiceNotFound
+	}
+	invoice = copyInvoice(invoice)
+	return &invoice, nil
+}
+
+func (db *testDatabase) UpdateInvoice(invoice *database.Invoice) error {
+	db.Lock()
+	defer db.Unlock()
+
+	db.invoices[invoice.Token] = copyInvoice(*invoice)
+	return nil
+}
+
+// newTestPaymentServer returns a server with an approved invoice which is
+// awaiting its payment, whose status changes are accepted by a stand-in
+// politeiad.
+func newTestPaymentServer(t *testing.T) (*cmswww, *memory.Memory, func()) {
+	log.SetLevel(slog.LevelOff)
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	politeiad := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var uvm pd.UpdateVettedMetadata
+			err := json.NewDecoder(r.Body).Decode(&uvm)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			challenge, err := hex.DecodeString(uvm.Challenge)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			signature := id.SignMessage(challenge)
+			json.NewEncoder(w).Encode(pd.UpdateVettedMetadataReply{
+				Response: hex.EncodeToString(signature[:]),
+			})
+		}))
+
+	now := time.Now().Unix()
+	db := &testDatabase{
+		invoices: map[string]database.Invoice{
+			testInvoiceToken: {
+				Token:  testInvoiceToken,
+				Status: v1.InvoiceStatusApproved,
+				Payments: []database.InvoicePayment{
+					{
+						Address:     testPaymentAddress,
+						Amount:      testPaymentAmount,
+						TxNotBefore: now - 60,
+						PollExpiry:  now + 3600,
+					},
+				},
+			},
+		},
+	}
+
+	watcher := memory.New()
+	c := &cmswww{
+		cfg: &config{
+			RPCHost:                  politeiad.URL,
+			Identity:                 &id.Public,
+			MinConfirmationsRequired: 2,
+			PaymentPollWorkers:       2,
+			PaymentTolerance:         0.001,
+		},
+		db:             db,
+		watcher:        watcher,
+		client:         politeiad.Client(),
+		polledPayments: make(map[string]polledPayment),
+		pollQuit:       make(chan struct{}),
+	}
+
+	invoice := db.invoices[testInvoiceToken]
+	c.addInvoiceForPolling(testInvoiceToken, &invoice.Payments[0])
+
+	return c, watcher, politeiad.Close
+}
+
+func TestUpdateInvoicePayment(t *testing.T) {
+	now := time.Now().Unix()
+	tests := []struct {
+		name           string
+		txs            []chain.Tx
+		paid           bool
+		status         v1.InvoiceStatusT
+		amountReceived uint64
+		reason         string
+	}{
+		{
+			name:   "no payment",
+			status: v1.InvoiceStatusApproved,
+		},
+		{
+			name: "below min confirmations",
+			txs: []chain.Tx{
+				{TxID: "tx1", Amount: testPaymentAmount, Timestamp: now,
+					Confirmations: 1},
+			},
+			status: v1.InvoiceStatusApproved,
+		},
+		{
+			name: "partial",
+			txs: []chain.Tx{
+				{TxID: "tx1", Amount: testPaymentAmount / 2, Timestamp: now,
+					Confirmations: 2},
+				{TxID: "tx2", Amount: testPaymentAmount / 2, Timestamp: now,
+					Confirmations: 1},
+			},
+			status:         v1.InvoiceStatusPartiallyPaid,
+			amountReceived: testPaymentAmount / 2,
+			reason:         "partial payment",
+		},
+		{
+			name: "within tolerance",
+			txs: []chain.Tx{
+				{TxID: "tx1", Amount: testPaymentAmount - 50000,
+					Timestamp: now, Confirmations: 6},
+			},
+			paid:           true,
+			status:         v1.InvoiceStatusPaid,
+			amountReceived: testPaymentAmount - 50000,
+			reason:         "underpaid",
+		},
+		{
+			name: "overpaid",
+			txs: []chain.Tx{
+				{TxID: "tx1", Amount: testPaymentAmount, Timestamp: now,
+					Confirmations: 3},
+				{TxID: "tx2", Amount: testPaymentAmount / 10,
+					Timestamp: now, Confirmations: 2},
+			},
+			paid:           true,
+			status:         v1.InvoiceStatusPaid,
+			amountReceived: testPaymentAmount + testPaymentAmount/10,
+			reason:         "overpaid",
+		},
+	}
+
+	for _, test := range tests {
+		c, _, closeFn := newTestPaymentServer(t)
+
+		paid, err := c.updateInvoicePayment(testInvoiceToken,
+			testPaymentAddress, test.txs)
+		closeFn()
+		if err != nil {
+			t.Errorf("%v: updateInvoicePayment: %v", test.name, err)
+			continue
+		}
+		if paid != test.paid {
+			t.Errorf("%v: got paid %v, want %v", test.name, paid, test.paid)
+		}
+
+		invoice, err := c.db.GetInvoiceByToken(testInvoiceToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if invoice.Status != test.status {
+			t.Errorf("%v: got status %v, want %v", test.name,
+				invoice.Status, test.status)
+		}
+		payment := invoice.Payments[0]
+		if payment.AmountReceived != test.amountReceived {
+			t.Errorf("%v: got amount received %v, want %v", test.name,
+				payment.AmountReceived, test.amountReceived)
+		}
+
+		if test.reason == "" {
+			if len(invoice.Changes) != 0 {
+				t.Errorf("%v: got %v changes, want none", test.name,
+					len(invoice.Changes))
+			}
+			continue
+		}
+		if len(invoice.Changes) != 1 {
+			t.Errorf("%v: got %v changes, want 1", test.name,
+				len(invoice.Changes))
+			continue
+		}
+		if !strings.Contains(invoice.Changes[0].Reason, test.reason) {
+			t.Errorf("%v: got reason %q, want it to contain %q",
+				test.name, invoice.Changes[0].Reason, test.reason)
+		}
+	}
+}
+
+func TestCheckForInvoicePayments(t *testing.T) {
+	c, watcher, closeFn := newTestPaymentServer(t)
+	defer closeFn()
+
+	checkPayments := func() map[string]polledPayment {
+		shouldContinue, paymentsToRemove := c.checkForInvoicePayments(
+			c.createPolledPaymentsCopy())
+		if !shouldContinue {
+			t.Fatal("payment checker stopped")
+		}
+		c.removeInvoicesFromPolling(paymentsToRemove)
+		return paymentsToRemove
+	}
+
+	expectStatus := func(status v1.InvoiceStatusT) {
+		invoice, err := c.db.GetInvoiceByToken(testInvoiceToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if invoice.Status != status {
+			t.Fatalf("got status %v, want %v", invoice.Status, status)
+		}
+	}
+
+	// Nothing has been sent yet.
+	if removed := checkPayments(); len(removed) != 0 {
+		t.Fatalf("got %v payments removed, want none", len(removed))
+	}
+	expectStatus(v1.InvoiceStatusApproved)
+
+	// Half of the payment is sent, but isn't confirmed yet.
+	now := time.Now().Unix()
+	watcher.AddTx(testPaymentAddress, chain.Tx{
+		TxID:      "tx1",
+		Amount:    testPaymentAmount / 2,
+		Timestamp: now,
+	})
+	checkPayments()
+	expectStatus(v1.InvoiceStatusApproved)
+
+	// Once it's confirmed the invoice is partially paid, and still polled.
+	watcher.SetConfirmations("tx1", 2)
+	if removed := checkPayments(); len(removed) != 0 {
+		t.Fatalf("got %v payments removed, want none", len(removed))
+	}
+	expectStatus(v1.InvoiceStatusPartiallyPaid)
+
+	// The rest of the payment completes the invoice, which stops being
+	// polled.
+	watcher.AddTx(testPaymentAddress, chain.Tx{
+		TxID:          "tx2",
+		Amount:        testPaymentAmount / 2,
+		Timestamp:     now,
+		Confirmations: 2,
+	})
+	if removed := checkPayments(); len(removed) != 1 {
+		t.Fatalf("got %v payments removed, want 1", len(removed))
+	}
+	expectStatus(v1.InvoiceStatusPaid)
+	if len(c.polledPayments) != 0 {
+		t.Fatalf("got %v payments polled, want none",
+			len(c.polledPayments))
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/dcrutil"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/database"
+)
+
+// validatePayoutBatchFormat verifies that a payout batch can be created in
+// the requested format; an empty format means that no batch is requested.
+func validatePayoutBatchFormat(format string) error {
+	switch format {
+	case "", v1.PayoutBatchFormatJSON, v1.PayoutBatchFormatCSV:
+		return nil
+	}
+
+	return v1.UserError{
+		ErrorCode: v1.ErrorStatusInvalidInput,
+		ErrorContext: []string{fmt.Sprintf("unsupported payout batch "+
+			"format %v", format)},
+	}
+}
+
+// convertToAtoms converts an amount in any currency to atoms at a rate given
+// in cents of that currency per DCR. The conversion is done in integers and
+// the result is rounded to the nearest atom, with halves rounded up.
+func convertToAtoms(amount, dcrRateCents uint64) (uint64, error) {
+	if dcrRateCents == 0 {
+		return 0, fmt.Errorf("invalid DCR rate of 0")
+	}
+
+	const atomsPerCent = 100 * dcrutil.AtomsPerCoin
+	if amount > (math.MaxUint64-dcrRateCents/2)/atomsPerCent {
+		return 0, fmt.Errorf("amount of %v is too large to convert", amount)
+	}
+
+	return (amount*atomsPerCent + dcrRateCents/2) / dcrRateCents, nil
+}
+
+// formatCents formats an amount in cents, such as a rate in cents per DCR,
+// in whole units of its currency.
+func formatCents(cents uint64) string {
+	return fmt.Sprintf("%v.%02d", cents/100, cents%100)
+}
+
+// paymentRates returns the currency in which a payment was quoted, along with
+// the DCR rates it was quoted at keyed by currency. Older payments were always
+// quoted in USD.
+func paymentRates(payment *database.InvoicePayment) (string, map[string]uint64) {
+	rates := map[string]uint64{
+		v1.PolicyInvoiceCurrency: payment.DCRUSDRateCents,
+	}
+	if payment.Currency == "" {
+		return v1.PolicyInvoiceCurrency, rates
+	}
+
+	rates[payment.Currency] = payment.DCRRateCents
+	return payment.Currency, rates
+}
+
+// newPayout returns the payout for the outstanding payment of an invoice, or
+// nil if there's nothing to pay. Funds which have already been received for
+// a partially paid invoice are deducted from the payout.
+func newPayout(dbInvoice *database.Invoice) *v1.Payout {
+	payment := outstandingInvoicePayment(dbInvoice)
+	if payment == nil || payment.AmountReceived >= payment.Amount {
+		return nil
+	}
+
+	// A remainder below dust can't be sent, so the invoice has to be
+	// reconciled instead.
+	atoms := payment.Amount - payment.AmountReceived
+	if atoms < dust {
+		return nil
+	}
+
+	currency, rates := paymentRates(payment)
+	return &v1.Payout{
+		Address:         payment.Address,
+		Atoms:           atoms,
+		DCRUSDRateCents: payment.DCRUSDRateCents,
+		Currency:        currency,
+		DCRRateCents:    rates[currency],
+		Token:           dbInvoice.Token,
+		Username:        dbInvoice.Username,
+	}
+}
+
+// encodePayoutBatchCSV encodes a payout batch as CSV, with the details of the
+// batch in comments at the top.
+func encodePayoutBatchCSV(batch *v1.PayoutBatch) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%c month: %v/%v\n", v1.PolicyInvoiceCommentChar,
+		batch.Month, batch.Year)
+	fmt.Fprintf(&buf, "%c dcrusdrate: %v\n", v1.PolicyInvoiceCommentChar,
+		formatCents(batch.DCRUSDRateCents))
+	for _, currency := range v1.PolicyInvoiceCurrencies {
+		if currency == v1.PolicyInvoiceCurrency ||
+			batch.DCRRates[currency] == 0 {
+			continue
+		}
+		fmt.Fprintf(&buf, "%c dcr%vrate: %v\n", v1.PolicyInvoiceCommentChar,
+			strings.ToLower(currency), formatCents(batch.DCRRates[currency]))
+	}
+	fmt.Fprintf(&buf, "%c timestamp: %v\n", v1.PolicyInvoiceCommentChar,
+		batch.Timestamp)
+
+	w := csv.NewWriter(&buf)
+	err := w.Write([]string{"address", "atoms", "dcr", "dcrusdrate",
+		"currency", "dcrrate", "token", "username"})
+	if err != nil {
+		return nil, err
+	}
+	for _, payout := range batch.Payouts {
+		err = w.Write([]string{
+			payout.Address,
+			strconv.FormatUint(payout.Atoms, 10),
+			strconv.FormatFloat(dcrutil.Amount(payout.Atoms).ToCoin(), 'f',
+				-1, 64),
+			formatCents(payout.DCRUSDRateCents),
+			payout.Currency,
+			formatCents(payout.DCRRateCents),
+			payout.Token,
+			payout.Username,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
+
+// createPayoutBatch encodes the payouts in the requested format and signs
+// the result with the server identity. The rates are the ones used for new
+// quotes, keyed by currency.
+func (c *cmswww) createPayoutBatch(pi *v1.PayInvoices, rates map[string]uint64, payouts []v1.Payout) (*v1.SignedPayoutBatch, error) {
+	batch := v1.PayoutBatch{
+		Month:           pi.Month,
+		Year:            pi.Year,
+		DCRUSDRateCents: pi.DCRUSDRateCents,
+		DCRRates:        rates,
+		Timestamp:       time.Now().Unix(),
+		Payouts:         payouts,
+		SendToMany:      make(map[string]float64, len(payouts)),
+	}
+	for _, payout := range payouts {
+		batch.SendToMany[payout.Address] += dcrutil.Amount(
+			payout.Atoms).ToCoin()
+	}
+
+	var (
+		payload []byte
+		err     error
+	)
+	switch pi.BatchFormat {
+	case v1.PayoutBatchFormatJSON:
+		payload, err = json.MarshalIndent(batch, "", "  ")
+	case v1.PayoutBatchFormatCSV:
+		payload, err = encodePayoutBatchCSV(&batch)
+	default:
+		err = fmt.Errorf("unsupported payout batch format %v",
+			pi.BatchFormat)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signature := c.identity.SignMessage(payload)
+	return &v1.SignedPayoutBatch{
+		Format:    pi.BatchFormat,
+		Payload:   string(payload),
+		PublicKey: hex.EncodeToString(c.identity.Public.Key[:]),
+		Signature: hex.EncodeToString(signature[:]),
+	}, nil
+}
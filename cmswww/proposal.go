@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/database"
+)
+
+// getProposalSpending returns the spend per proposal across all approved and
+// paid invoices, along with the budget of each proposal. Disputed line items
+// are excluded since they won't be paid. Spend billed in other currencies is
+// normalized to the base currency with the rates of the month it was billed
+// in; proposals with spend in a month which has no rate are flagged, since
+// their spend is only partly known.
+func (c *cmswww) getProposalSpending() (map[string]*v1.ProposalSpend, error) {
+	spending := make(map[string]*v1.ProposalSpend)
+
+	budgets, err := c.db.GetProposalBudgets()
+	if err != nil {
+		return nil, err
+	}
+	for _, budget := range budgets {
+		if budget.BudgetUSD == 0 {
+			continue
+		}
+
+		spending[budget.Proposal] = &v1.ProposalSpend{
+			Proposal:  budget.Proposal,
+			BudgetUSD: budget.BudgetUSD,
+		}
+	}
+
+	invoices, err := c.db.GetInvoices(database.InvoicesRequest{
+		StatusMap: map[v1.InvoiceStatusT]bool{
+			v1.InvoiceStatusApproved:      true,
+			v1.InvoiceStatusPartiallyPaid: true,
+			v1.InvoiceStatusPaid:          true,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	monthlyRates := make(map[string]map[string]uint64) // [month/year]rates
+	for _, invoice := range invoices {
+		err := c.fetchInvoiceFileIfNecessary(&invoice)
+		if err != nil {
+			return nil, err
+		}
+
+		month := fmt.Sprintf("%v/%v", invoice.Month, invoice.Year)
+		rates, ok := monthlyRates[month]
+		if !ok {
+			rates, err = c.monthlyRates(invoice.Month, invoice.Year, false)
+			if err != nil {
+				return nil, err
+			}
+			monthlyRates[month] = rates
+		}
+
+		invoiceReview, err := c.createInvoiceReview(&invoice, rates)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, lineItem := range invoiceReview.LineItems {
+			proposal := strings.TrimSpace(lineItem.Proposal)
+			if proposal == "" ||
+				lineItem.Status == v1.LineItemStatusDisputed {
+				continue
+			}
+
+			ps, ok := spending[proposal]
+			if !ok {
+				ps = &v1.ProposalSpend{
+					Proposal: proposal,
+				}
+				spending[proposal] = ps
+			}
+
+			cost, ok := normalizeAmount(lineItem.TotalCost,
+				invoiceReview.Currency, rates)
+			if !ok {
+				// Without a rate the cost is unknown, so it's flagged
+				// rather than counted as nothing.
+				ps.SpendUnknown = true
+				continue
+			}
+			if invoice.Status == v1.InvoiceStatusPaid {
+				ps.PaidUSD += cost
+			} else {
+				ps.ApprovedUSD += cost
+			}
+		}
+	}
+
+	for _, ps := range spending {
+		ps.OverBudget = isOverBudget(ps, 0)
+	}
+
+	return spending, nil
+}
+
+// isOverBudget returns whether the spend of a proposal, plus the given
+// additional cost, exceeds its budget.
+func isOverBudget(ps *v1.ProposalSpend, additionalCostUSD uint64) bool {
+	return ps.BudgetUSD > 0 &&
+		ps.ApprovedUSD+ps.PaidUSD+additionalCostUSD > ps.BudgetUSD
+}
+
+// flagOverBudgetProposals sets the budget and current spend of each proposal
+// total, and flags the ones which would push their proposal over budget if
+// approved.
+func flagOverBudgetProposals(totals []v1.InvoiceProposalTotal, spending map[string]*v1.ProposalSpend) {
+	for idx := range totals {
+		ps, ok := spending[totals[idx].Proposal]
+		if !ok {
+			continue
+		}
+
+		totals[idx].BudgetUSD = ps.BudgetUSD
+		totals[idx].SpentUSD = ps.ApprovedUSD + ps.PaidUSD
+		totals[idx].SpendUnknown = ps.SpendUnknown
+		totals[idx].OverBudget = isOverBudget(ps, totals[idx].TotalCostUSD)
+	}
+}
+
+// HandleProposalSpending returns the spend billed against proposals across
+// all approved and paid invoices.
+func (c *cmswww) HandleProposalSpending(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	ps := req.(*v1.ProposalSpending)
+
+	spending, err := c.getProposalSpending()
+	if err != nil {
+		return nil, err
+	}
+
+	psr := v1.ProposalSpendingReply{
+		Proposals: make([]v1.ProposalSpend, 0, len(spending)),
+	}
+
+	proposal := strings.TrimSpace(ps.Proposal)
+	if proposal != "" {
+		proposalSpend, ok := spending[proposal]
+		if !ok {
+			proposalSpend = &v1.ProposalSpend{
+				Proposal: proposal,
+			}
+		}
+		psr.Proposals = append(psr.Proposals, *proposalSpend)
+		return &psr, nil
+	}
+
+	for _, proposalSpend := range spending {
+		psr.Proposals = append(psr.Proposals, *proposalSpend)
+	}
+	sort.Slice(psr.Proposals, func(i, j int) bool {
+		return psr.Proposals[i].Proposal < psr.Proposals[j].Proposal
+	})
+
+	return &psr, nil
+}
+
+// HandleSetProposalBudget sets the maximum amount that can be billed against
+// a proposal.
+func (c *cmswww) HandleSetProposalBudget(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	spb := req.(*v1.SetProposalBudget)
+
+	proposal := strings.TrimSpace(spb.Proposal)
+	if proposal == "" {
+		return nil, v1.UserError{
+			ErrorCode:    v1.ErrorStatusInvalidInput,
+			ErrorContext: []string{"proposal token is required"},
+		}
+	}
+
+	err := c.db.SetProposalBudget(&database.ProposalBudget{
+		Proposal:  proposal,
+		BudgetUSD: spb.BudgetUSD,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Log the action in the admin log.
+	c.Lock()
+	err = c.logAdminAction(user, fmt.Sprintf("set proposal budget,%v,%v",
+		proposal, spb.BudgetUSD))
+	c.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	spending, err := c.getProposalSpending()
+	if err != nil {
+		return nil, err
+	}
+
+	spbr := v1.SetProposalBudgetReply{
+		Proposal: v1.ProposalSpend{
+			Proposal: proposal,
+		},
+	}
+	if proposalSpend, ok := spending[proposal]; ok {
+		spbr.Proposal = *proposalSpend
+	}
+	return &spbr, nil
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/database"
+	"github.com/decred/contractor-mgmt/cmswww/rate"
+)
+
+// validateRateMonth verifies that a month is valid and has ended, so that
+// its average rate can be computed.
+func validateRateMonth(month, year uint16) error {
+	if month < 1 || month > 12 || year == 0 {
+		return v1.UserError{
+			ErrorCode:    v1.ErrorStatusInvalidInput,
+			ErrorContext: []string{fmt.Sprintf("invalid month %v/%v", month, year)},
+		}
+	}
+
+	_, endOfMonth := rate.MonthBounds(month, year)
+	if time.Now().Before(endOfMonth) {
+		return v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+			ErrorContext: []string{fmt.Sprintf("the month %v/%v has not "+
+				"ended yet", month, year)},
+		}
+	}
+
+	return nil
+}
+
+// parseCurrency returns the upper case code of a currency in which invoices
+// can be billed. An empty currency is the base currency.
+func parseCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return v1.PolicyInvoiceCurrency, nil
+	}
+
+	for _, supported := range v1.PolicyInvoiceCurrencies {
+		if currency == supported {
+			return currency, nil
+		}
+	}
+	return "", v1.UserError{
+		ErrorCode:    v1.ErrorStatusInvalidInput,
+		ErrorContext: []string{fmt.Sprintf("unsupported currency %v", currency)},
+	}
+}
+
+// fetchMonthlyRate computes the DCR rate of a month in a currency as the mean
+// of the average prices provided by the rate sources, leaving out the prices
+// which deviate too much from the median. Sources which fail are stored along
+// with their error, so that it's clear what the rate is based on, while
+// sources which don't support the currency are left out.
+func (c *cmswww) fetchMonthlyRate(month, year uint16, currency string) (*database.MonthlyRate, error) {
+	startOfMonth, endOfMonth := rate.MonthBounds(month, year)
+
+	monthlyRate := database.MonthlyRate{
+		Month:     month,
+		Year:      year,
+		Currency:  currency,
+		Sources:   make([]database.MonthlyRateSource, 0, len(c.rateSources)),
+		Timestamp: time.Now().Unix(),
+	}
+	var (
+		rates      []uint64
+		sourceIdxs []int // Index of the source of each rate
+	)
+	for _, source := range c.rateSources {
+		monthlyRateSource := database.MonthlyRateSource{
+			Name: source.Name(),
+		}
+
+		price, err := source.MonthlyAverage(currency, startOfMonth,
+			endOfMonth)
+		if err == rate.ErrUnsupportedCurrency {
+			continue
+		}
+		if err == nil {
+			monthlyRateSource.DCRRateCents, err = rate.ToCents(price)
+		}
+		if err != nil {
+			log.Warnf("Unable to fetch the %v %v rate for %v/%v: %v",
+				source.Name(), currency, month, year, err)
+			monthlyRateSource.Error = err.Error()
+		} else {
+			rates = append(rates, monthlyRateSource.DCRRateCents)
+			sourceIdxs = append(sourceIdxs, len(monthlyRate.Sources))
+		}
+
+		monthlyRate.Sources = append(monthlyRate.Sources, monthlyRateSource)
+	}
+
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("none of the rate sources provided a %v "+
+			"rate for %v/%v", currency, month, year)
+	}
+
+	var accepted []uint64
+	for idx, outlier := range rate.Outliers(rates, c.cfg.RateMaxDeviation) {
+		if outlier {
+			source := &monthlyRate.Sources[sourceIdxs[idx]]
+			log.Warnf("Rejecting the %v rate for %v/%v of %v %v as an "+
+				"outlier", source.Name, month, year,
+				formatCents(source.DCRRateCents), currency)
+			source.Outlier = true
+			continue
+		}
+		accepted = append(accepted, rates[idx])
+	}
+	if len(accepted) == 0 {
+		return nil, fmt.Errorf("the %v rates provided for %v/%v deviate "+
+			"too much from each other", currency, month, year)
+	}
+
+	monthlyRate.DCRRateCents = rate.Mean(accepted)
+	return &monthlyRate, nil
+}
+
+// monthlyRates returns the stored DCR rates of a month in cents per DCR,
+// keyed by currency, for the supported currencies which have one. If
+// lockedOnly is set, rates which haven't been locked are left out.
+func (c *cmswww) monthlyRates(month, year uint16, lockedOnly bool) (map[string]uint64, error) {
+	rates := make(map[string]uint64, len(v1.PolicyInvoiceCurrencies))
+	for _, currency := range v1.PolicyInvoiceCurrencies {
+		monthlyRate, err := c.db.GetMonthlyRate(month, year, currency)
+		if err != nil {
+			if err == database.ErrMonthlyRateNotFound {
+				continue
+			}
+			return nil, err
+		}
+
+		if lockedOnly && !monthlyRate.Locked {
+			continue
+		}
+		rates[currency] = monthlyRate.DCRRateCents
+	}
+	return rates, nil
+}
+
+// normalizeAmount converts an amount in the given currency to the base
+// currency using the DCR rates of both. It returns false if either rate is
+// missing.
+func normalizeAmount(amount uint64, currency string, rates map[string]uint64) (uint64, bool) {
+	if currency == v1.PolicyInvoiceCurrency {
+		return amount, true
+	}
+
+	normalized, err := rate.Convert(amount, rates[currency],
+		rates[v1.PolicyInvoiceCurrency])
+	if err != nil {
+		return 0, false
+	}
+	return normalized, true
+}
+
+// HandleMonthlyRate returns the stored DCR rate of a month in a currency.
+func (c *cmswww) HandleMonthlyRate(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	mr := req.(*v1.MonthlyRate)
+
+	currency, err := parseCurrency(mr.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	monthlyRate, err := c.db.GetMonthlyRate(mr.Month, mr.Year, currency)
+	if err != nil {
+		if err == database.ErrMonthlyRateNotFound {
+			return nil, v1.UserError{
+				ErrorCode: v1.ErrorStatusMonthlyRateNotFound,
+			}
+		}
+		return nil, err
+	}
+
+	return &v1.MonthlyRateReply{
+		Rate: convertDatabaseMonthlyRateToMonthlyRate(monthlyRate),
+	}, nil
+}
+
+// HandleFetchMonthlyRate computes the DCR rate of a month in a currency from
+// the rate sources and stores it, replacing the previous rate unless it's
+// locked.
+func (c *cmswww) HandleFetchMonthlyRate(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	fmr := req.(*v1.FetchMonthlyRate)
+
+	err := validateRateMonth(fmr.Month, fmr.Year)
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := parseCurrency(fmr.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// The sources are queried without the lock held, since it can take a
+	// while.
+	monthlyRate, err := c.fetchMonthlyRate(fmr.Month, fmr.Year, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	existingRate, err := c.db.GetMonthlyRate(fmr.Month, fmr.Year, currency)
+	if err != nil && err != database.ErrMonthlyRateNotFound {
+		return nil, err
+	}
+	if err == nil && existingRate.Locked {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusMonthlyRateLocked,
+		}
+	}
+
+	err = c.db.SetMonthlyRate(monthlyRate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Log the action in the admin log.
+	err = c.logAdminAction(user, fmt.Sprintf(
+		"fetched monthly rate,%v/%v,%v,%v", fmr.Month, fmr.Year, currency,
+		formatCents(monthlyRate.DCRRateCents)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.FetchMonthlyRateReply{
+		Rate: convertDatabaseMonthlyRateToMonthlyRate(monthlyRate),
+	}, nil
+}
+
+// HandleLockMonthlyRate marks the stored DCR rate of a month in a currency as
+// agreed on, after which it's used to pay the invoices of the month billed in
+// that currency and can no longer be fetched again.
+func (c *cmswww) HandleLockMonthlyRate(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	lmr := req.(*v1.LockMonthlyRate)
+
+	err := checkPublicKeyAndSignature(user, lmr.PublicKey, lmr.Signature,
+		strconv.FormatUint(uint64(lmr.Month), 10),
+		strconv.FormatUint(uint64(lmr.Year), 10), lmr.Currency,
+		strconv.FormatUint(lmr.DCRRateCents, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := parseCurrency(lmr.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	monthlyRate, err := c.db.GetMonthlyRate(lmr.Month, lmr.Year, currency)
+	if err != nil {
+		if err == database.ErrMonthlyRateNotFound {
+			return nil, v1.UserError{
+				ErrorCode: v1.ErrorStatusMonthlyRateNotFound,
+			}
+		}
+		return nil, err
+	}
+
+	if monthlyRate.Locked {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusMonthlyRateLocked,
+		}
+	}
+
+	// The rate could have been fetched again since the admin reviewed it.
+	if monthlyRate.DCRRateCents != lmr.DCRRateCents {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidInput,
+			ErrorContext: []string{fmt.Sprintf("the stored rate is %v %v",
+				formatCents(monthlyRate.DCRRateCents), currency)},
+		}
+	}
+
+	monthlyRate.Locked = true
+	monthlyRate.LockedBy = lmr.PublicKey
+	monthlyRate.LockedAt = time.Now().Unix()
+	err = c.db.SetMonthlyRate(monthlyRate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Log the action in the admin log.
+	err = c.logAdminAction(user, fmt.Sprintf(
+		"locked monthly rate,%v/%v,%v,%v", lmr.Month, lmr.Year, currency,
+		formatCents(monthlyRate.DCRRateCents)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.LockMonthlyRateReply{
+		Rate: convertDatabaseMonthlyRateToMonthlyRate(monthlyRate),
+	}, nil
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/contractor-mgmt/cmswww/rate"
+)
+
+const (
+	// DefaultURL is the base URL of the Binance API.
+	DefaultURL = "https://api.binance.com"
+
+	klinesPath = "/api/v3/klines"
+
+	// symbol is the market used for the rate; USDT is taken to be worth
+	// one USD.
+	symbol = "DCRUSDT"
+)
+
+var (
+	_ rate.Source = (*binance)(nil)
+)
+
+// kline is a daily candlestick, which is returned as an array of mixed
+// numbers and strings.
+type kline struct {
+	OpenTime    int64
+	Volume      float64 // Volume in DCR
+	QuoteVolume float64 // Volume in USDT
+}
+
+func (k *kline) UnmarshalJSON(data []byte) error {
+	var v []interface{}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return fmt.Errorf("error while decoding: %v", err)
+	}
+	if len(v) < 8 {
+		return fmt.Errorf("unexpected kline: %v", string(data))
+	}
+
+	openTime, ok := v[0].(float64)
+	if !ok {
+		return fmt.Errorf("invalid kline open time: %v", v[0])
+	}
+	volume, ok := v[5].(string)
+	if !ok {
+		return fmt.Errorf("invalid kline volume: %v", v[5])
+	}
+	quoteVolume, ok := v[7].(string)
+	if !ok {
+		return fmt.Errorf("invalid kline quote volume: %v", v[7])
+	}
+
+	k.OpenTime = int64(openTime)
+	k.Volume, err = strconv.ParseFloat(volume, 64)
+	if err != nil {
+		return err
+	}
+	k.QuoteVolume, err = strconv.ParseFloat(quoteVolume, 64)
+	return err
+}
+
+// binance computes the DCR/USD rate as the volume weighted average price of
+// the DCR/USDT market of the Binance exchange.
+type binance struct {
+	url    string
+	client *http.Client
+}
+
+// Name satisfies the rate source interface.
+func (b *binance) Name() string {
+	return "Binance"
+}
+
+// MonthlyAverage satisfies the rate source interface. Only USD prices are
+// provided, through the USDT market.
+func (b *binance) MonthlyAverage(currency string, startOfMonth, endOfMonth time.Time) (float64, error) {
+	if currency != "USD" {
+		return 0, rate.ErrUnsupportedCurrency
+	}
+
+	v := url.Values{}
+	v.Set("symbol", symbol)
+	v.Set("interval", "1d")
+	v.Set("startTime", strconv.FormatInt(startOfMonth.Unix()*1000, 10))
+	v.Set("endTime", strconv.FormatInt(endOfMonth.Unix()*1000, 10))
+	v.Set("limit", "31")
+
+	var klines []kline
+	err := rate.GetJSON(b.client, b.url+klinesPath+"?"+v.Encode(), &klines)
+	if err != nil {
+		return 0, err
+	}
+	if len(klines) == 0 {
+		return 0, fmt.Errorf("no results returned")
+	}
+
+	var volume, quoteVolume float64
+	for _, k := range klines {
+		volume += k.Volume
+		quoteVolume += k.QuoteVolume
+	}
+	if volume == 0 {
+		return 0, fmt.Errorf("no trading volume")
+	}
+
+	return quoteVolume / volume, nil
+}
+
+// New returns a rate source which uses the Binance API at the given base URL.
+func New(url string) rate.Source {
+	return &binance{
+		url: strings.TrimSuffix(url, "/"),
+		client: &http.Client{
+			Timeout: time.Minute,
+		},
+	}
+}
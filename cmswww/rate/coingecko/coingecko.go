@@ -0,0 +1,80 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coingecko
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/contractor-mgmt/cmswww/rate"
+)
+
+const (
+	// DefaultURL is the base URL of the CoinGecko API.
+	DefaultURL = "https://api.coingecko.com/api/v3"
+
+	marketChartRangePath = "/coins/decred/market_chart/range"
+)
+
+var (
+	_ rate.Source = (*coingecko)(nil)
+)
+
+type marketChartRangeResponse struct {
+	Prices [][2]float64 `json:"prices"` // [timestamp in ms, price]
+}
+
+// coingecko computes the DCR rate as the average of the prices reported by
+// CoinGecko over the month, which are aggregated across exchanges. CoinGecko
+// provides prices in most fiat currencies.
+type coingecko struct {
+	url    string
+	client *http.Client
+}
+
+// Name satisfies the rate source interface.
+func (c *coingecko) Name() string {
+	return "CoinGecko"
+}
+
+// MonthlyAverage satisfies the rate source interface.
+func (c *coingecko) MonthlyAverage(currency string, startOfMonth, endOfMonth time.Time) (float64, error) {
+	v := url.Values{}
+	v.Set("vs_currency", strings.ToLower(currency))
+	v.Set("from", strconv.FormatInt(startOfMonth.Unix(), 10))
+	v.Set("to", strconv.FormatInt(endOfMonth.Unix(), 10))
+
+	var resp marketChartRangeResponse
+	err := rate.GetJSON(c.client, c.url+marketChartRangePath+"?"+v.Encode(),
+		&resp)
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Prices) == 0 {
+		return 0, fmt.Errorf("no results returned")
+	}
+
+	var total float64
+	for _, price := range resp.Prices {
+		total += price[1]
+	}
+
+	return total / float64(len(resp.Prices)), nil
+}
+
+// New returns a rate source which uses the CoinGecko API at the given base
+// URL.
+func New(url string) rate.Source {
+	return &coingecko{
+		url: strings.TrimSuffix(url, "/"),
+		client: &http.Client{
+			Timeout: time.Minute,
+		},
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coinmetrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/decred/contractor-mgmt/cmswww/rate"
+)
+
+const (
+	// DefaultURL is the base URL of the Coin Metrics API.
+	DefaultURL = "https://coinmetrics.io/api/v1"
+
+	getAssetDataPath = "/get_asset_data_for_time_range/dcr/%v/%v/%v"
+)
+
+var (
+	_ rate.Source = (*coinmetrics)(nil)
+)
+
+type getAssetDataResponse struct {
+	Result []getAssetDataResult `json:"result"`
+}
+
+// getAssetDataResult is a single data point, which is returned as a
+// [timestamp, value] array.
+type getAssetDataResult struct {
+	Timestamp int64
+	Data      float64
+}
+
+func (r *getAssetDataResult) UnmarshalJSON(data []byte) error {
+	var v []float64
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return fmt.Errorf("error while decoding: %v", err)
+	}
+	if len(v) != 2 {
+		return fmt.Errorf("unexpected data point: %v", string(data))
+	}
+
+	r.Timestamp = int64(v[0])
+	r.Data = v[1]
+	return nil
+}
+
+// coinmetrics computes the DCR/USD rate as the daily price weighted by the
+// exchange volume, as reported by Coin Metrics.
+type coinmetrics struct {
+	url    string
+	client *http.Client
+}
+
+// Name satisfies the rate source interface.
+func (c *coinmetrics) Name() string {
+	return "Coinmetrics"
+}
+
+func (c *coinmetrics) assetData(dataType string, startOfMonth, endOfMonth time.Time) ([]float64, error) {
+	url := c.url + fmt.Sprintf(getAssetDataPath, dataType,
+		startOfMonth.Unix(), endOfMonth.Unix())
+
+	var resp getAssetDataResponse
+	err := rate.GetJSON(c.client, url, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Result) == 0 {
+		return nil, fmt.Errorf("%v: no results returned", dataType)
+	}
+
+	data := make([]float64, 0, len(resp.Result))
+	for _, v := range resp.Result {
+		data = append(data, v.Data)
+	}
+	return data, nil
+}
+
+// MonthlyAverage satisfies the rate source interface. Coinmetrics only
+// provides prices in USD.
+func (c *coinmetrics) MonthlyAverage(currency string, startOfMonth, endOfMonth time.Time) (float64, error) {
+	if currency != "USD" {
+		return 0, rate.ErrUnsupportedCurrency
+	}
+
+	prices, err := c.assetData("price(usd)", startOfMonth, endOfMonth)
+	if err != nil {
+		return 0, err
+	}
+
+	exchangeVolumes, err := c.assetData("exchangevolume(usd)",
+		startOfMonth, endOfMonth)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(prices) != len(exchangeVolumes) {
+		return 0, fmt.Errorf("length of data returned is inconsistent: %v %v",
+			len(prices), len(exchangeVolumes))
+	}
+
+	var totalVolume float64
+	for _, volume := range exchangeVolumes {
+		totalVolume += volume
+	}
+	if totalVolume == 0 {
+		return 0, fmt.Errorf("no exchange volume")
+	}
+
+	var weightedAverage float64
+	for idx, price := range prices {
+		weightedAverage += price * (exchangeVolumes[idx] / totalVolume)
+	}
+
+	return weightedAverage, nil
+}
+
+// New returns a rate source which uses the Coin Metrics API at the given base
+// URL.
+func New(url string) rate.Source {
+	return &coinmetrics{
+		url: strings.TrimSuffix(url, "/"),
+		client: &http.Client{
+			Timeout: time.Minute,
+		},
+	}
+}
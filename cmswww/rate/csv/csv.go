@@ -0,0 +1,116 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package csv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/contractor-mgmt/cmswww/rate"
+)
+
+const (
+	dateFormat = "2006-01-02"
+)
+
+var (
+	_ rate.Source = (*csvFile)(nil)
+)
+
+// csvFile computes the DCR rate as the average of the daily prices in a local
+// CSV file. Each row contains a date in the YYYY-MM-DD format, the price of
+// DCR on that day and optionally the currency of the price, which defaults to
+// USD; an optional header row and lines starting with # are ignored. The file
+// is read every time a rate is computed, so it can be updated while the
+// server is running.
+type csvFile struct {
+	path string
+}
+
+// Name satisfies the rate source interface.
+func (c *csvFile) Name() string {
+	return "CSV"
+}
+
+// MonthlyAverage satisfies the rate source interface.
+func (c *csvFile) MonthlyAverage(currency string, startOfMonth, endOfMonth time.Time) (float64, error) {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var (
+		total  float64
+		prices int
+	)
+	for row := 1; ; row++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if len(record) != 2 && len(record) != 3 {
+			return 0, fmt.Errorf("expected 2 or 3 fields in row %v, found %v",
+				row, len(record))
+		}
+
+		date, err := time.Parse(dateFormat, strings.TrimSpace(record[0]))
+		if err != nil {
+			if row == 1 {
+				// Header row.
+				continue
+			}
+			return 0, fmt.Errorf("invalid date in row %v: %v", row,
+				record[0])
+		}
+		if date.Before(startOfMonth) || date.After(endOfMonth) {
+			continue
+		}
+
+		priceCurrency := "USD"
+		if len(record) == 3 {
+			priceCurrency = strings.ToUpper(strings.TrimSpace(record[2]))
+		}
+		if priceCurrency != currency {
+			continue
+		}
+
+		price, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil || price <= 0 {
+			return 0, fmt.Errorf("invalid price in row %v: %v", row,
+				record[1])
+		}
+
+		total += price
+		prices++
+	}
+
+	if prices == 0 {
+		return 0, fmt.Errorf("no %v prices for %v in %v", currency,
+			startOfMonth.Format("January 2006"), c.path)
+	}
+	return total / float64(prices), nil
+}
+
+// New returns a rate source which reads daily prices from the CSV file at
+// the given path.
+func New(path string) rate.Source {
+	return &csvFile{
+		path: path,
+	}
+}
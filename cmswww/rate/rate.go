@@ -0,0 +1,144 @@
+// Copyright (c) 2018 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrUnsupportedCurrency is returned by sources which don't provide
+	// prices in the requested currency.
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+)
+
+// Source is the interface that all providers of DCR market data used to
+// compute the monthly rates must implement.
+type Source interface {
+	// Name returns the name of the source, which is stored along with the
+	// rate it provided.
+	Name() string
+
+	// MonthlyAverage returns the average price of DCR in the given currency,
+	// which is an upper case ISO 4217 code, between the start and the end of
+	// a month. It returns ErrUnsupportedCurrency if the source has no prices
+	// in that currency.
+	MonthlyAverage(currency string, startOfMonth, endOfMonth time.Time) (float64, error)
+}
+
+// MonthBounds returns the first and the last instant of a month in UTC.
+func MonthBounds(month, year uint16) (time.Time, time.Time) {
+	startOfMonth := time.Date(int(year), time.Month(month), 1, 0, 0, 0, 0,
+		time.UTC)
+	endOfMonth := startOfMonth.AddDate(0, 1, 0).Add(-1 * time.Nanosecond)
+	return startOfMonth, endOfMonth
+}
+
+// ToCents converts a price to cents, rounded to the nearest cent.
+func ToCents(price float64) (uint64, error) {
+	if math.IsNaN(price) || price <= 0 || price >= math.MaxUint64/100 {
+		return 0, fmt.Errorf("invalid price: %v", price)
+	}
+
+	return uint64(math.Round(price * 100)), nil
+}
+
+// Convert converts an amount from one currency to another given the rates of
+// DCR in cents of each currency, so that any two currencies can be converted
+// through DCR. The result is rounded to the nearest unit, with halves rounded
+// up.
+func Convert(amount, fromRateCents, toRateCents uint64) (uint64, error) {
+	if fromRateCents == 0 || toRateCents == 0 {
+		return 0, fmt.Errorf("invalid rate of 0")
+	}
+	if amount > (math.MaxUint64-fromRateCents/2)/toRateCents {
+		return 0, fmt.Errorf("amount of %v is too large to convert", amount)
+	}
+
+	return (amount*toRateCents + fromRateCents/2) / fromRateCents, nil
+}
+
+// Median returns the median of the given rates, with halves rounded up when
+// there's an even number of rates. It returns 0 if there are no rates.
+func Median(rates []uint64) uint64 {
+	if len(rates) == 0 {
+		return 0
+	}
+
+	sorted := make([]uint64, len(rates))
+	copy(sorted, rates)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	l := len(sorted)
+	if l%2 == 1 {
+		return sorted[l/2]
+	}
+	return (sorted[l/2-1] + sorted[l/2] + 1) / 2
+}
+
+// Mean returns the mean of the given rates, with halves rounded up. It
+// returns 0 if there are no rates.
+func Mean(rates []uint64) uint64 {
+	if len(rates) == 0 {
+		return 0
+	}
+
+	var total uint64
+	for _, r := range rates {
+		total += r
+	}
+	l := uint64(len(rates))
+	return (total + l/2) / l
+}
+
+// Outliers returns whether each of the given rates deviates from the median
+// of the rates by more than maxDeviation, which is a fraction of the median.
+// Nothing is an outlier if maxDeviation is 0.
+func Outliers(rates []uint64, maxDeviation float64) []bool {
+	outliers := make([]bool, len(rates))
+	if maxDeviation == 0 {
+		return outliers
+	}
+
+	median := Median(rates)
+	maxDiff := uint64(float64(median) * maxDeviation)
+	for idx, r := range rates {
+		diff := r - median
+		if r < median {
+			diff = median - r
+		}
+		outliers[idx] = diff > maxDiff
+	}
+	return outliers
+}
+
+// GetJSON fetches the given URL and decodes its JSON reply, which is used by
+// the sources which query a web API.
+func GetJSON(client *http.Client, url string, reply interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v: %v", resp.Status, string(body))
+	}
+
+	return json.Unmarshal(body, reply)
+}
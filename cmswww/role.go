@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/database"
+)
+
+var (
+	// permissionRoles maps the permissions which aren't restricted to
+	// admins to the roles which grant them. Admins have every permission.
+	permissionRoles = map[permission][]v1.UserRoleT{
+		permissionViewInvoices: {
+			v1.UserRoleReviewer,
+			v1.UserRoleTreasurer,
+			v1.UserRoleAuditor,
+		},
+		permissionViewUsers: {
+			v1.UserRoleUserManager,
+			v1.UserRoleAuditor,
+		},
+		permissionReviewInvoices: {
+			v1.UserRoleReviewer,
+		},
+		permissionSetInvoiceStatus: {
+			v1.UserRoleReviewer,
+			v1.UserRoleTreasurer,
+		},
+		permissionPayInvoices: {
+			v1.UserRoleTreasurer,
+		},
+		permissionManageUsers: {
+			v1.UserRoleUserManager,
+		},
+	}
+)
+
+// userHasRole returns true if the user has been granted the role or is an
+// admin.
+func userHasRole(user *database.User, role v1.UserRoleT) bool {
+	if user == nil {
+		return false
+	}
+	if user.Admin {
+		return true
+	}
+
+	for _, r := range user.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// userHasPermission returns true if the user is allowed to use the routes
+// which require the permission.
+func userHasPermission(user *database.User, perm permission) bool {
+	switch perm {
+	case permissionPublic:
+		return true
+	case permissionLogin:
+		return user != nil
+	}
+
+	if user == nil {
+		return false
+	}
+	if user.Admin {
+		return true
+	}
+
+	for _, role := range permissionRoles[perm] {
+		if userHasRole(user, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPrivilegedUser returns true if the user is an admin or has been granted
+// any role, which are the users who must enable two-factor authentication
+// when the server requires it.
+func isPrivilegedUser(user *database.User) bool {
+	return user.Admin || len(user.Roles) > 0
+}
@@ -32,12 +32,12 @@ func (c *cmswww) addRoute(
 		handler = c.loadInventory(handler)
 	}
 	switch perm {
-	case permissionAdmin:
-		handler = logging(c.isLoggedInAsAdmin(handler))
+	case permissionPublic:
+		handler = logging(handler)
 	case permissionLogin:
 		handler = logging(c.isLoggedIn(handler))
 	default:
-		handler = logging(handler)
+		handler = logging(c.hasPermission(perm, handler))
 	}
 
 	// All handlers need to close the body
@@ -131,6 +131,10 @@ func (c *cmswww) SetupRoutes() {
 		permissionPublic, false)
 	c.addPostRoute(v1.RouteLogin, c.HandleLogin, new(v1.Login),
 		permissionPublic, false)
+	c.addPostRoute(v1.RouteLoginChallenge, c.HandleLoginChallenge,
+		new(v1.LoginChallenge), permissionPublic, false)
+	c.addPostRoute(v1.RouteLoginSignature, c.HandleLoginSignature,
+		new(v1.LoginSignature), permissionPublic, false)
 	c.addRoute(http.MethodPost, v1.RouteLogout, c.HandleLogout,
 		permissionPublic, false)
 	c.addPostRoute(v1.RouteResetPassword, c.HandleResetPassword,
@@ -145,10 +149,16 @@ func (c *cmswww) SetupRoutes() {
 		new(v1.ChangePassword), permissionLogin, false)
 	c.addPostRoute(v1.RouteSubmitInvoice, c.HandleSubmitInvoice,
 		new(v1.SubmitInvoice), permissionLogin, true)
+	c.addPostRoute(v1.RouteEditInvoice, c.HandleEditInvoice,
+		new(v1.EditInvoice), permissionLogin, true)
 	c.addGetRoute(v1.RouteInvoiceDetails, c.HandleInvoiceDetails,
 		new(v1.InvoiceDetails), permissionLogin, true)
 	c.addGetRoute(v1.RouteUserInvoices, c.HandleMyInvoices,
 		new(v1.MyInvoices), permissionLogin, true)
+	c.addPostRoute(v1.RouteNewInvoiceComment, c.HandleNewInvoiceComment,
+		new(v1.NewInvoiceComment), permissionLogin, true)
+	c.addGetRoute(v1.RouteInvoiceComments, c.HandleInvoiceComments,
+		new(v1.InvoiceComments), permissionLogin, true)
 	c.addPostRoute(v1.RouteEditUser, c.HandleEditUser, new(v1.EditUser),
 		permissionLogin, false)
 	c.addGetRoute(v1.RouteUserDetails, c.HandleUserDetails, new(v1.UserDetails),
@@ -156,18 +166,45 @@ func (c *cmswww) SetupRoutes() {
 	c.addPostRoute(v1.RouteEditUserExtendedPublicKey,
 		c.HandleEditUserExtendedPublicKey, new(v1.EditUserExtendedPublicKey),
 		permissionLogin, false)
-
-	// Routes that require being logged in as an admin user.
+	c.addPostRoute(v1.RouteEnrollTOTP, c.HandleEnrollTOTP,
+		new(v1.EnrollTOTP), permissionLogin, false)
+	c.addPostRoute(v1.RouteVerifyTOTP, c.HandleVerifyTOTP,
+		new(v1.VerifyTOTP), permissionLogin, false)
+	c.addPostRoute(v1.RouteDisableTOTP, c.HandleDisableTOTP,
+		new(v1.DisableTOTP), permissionLogin, false)
+
+	// Routes that require being logged in as an admin user, or as a user
+	// with a role which grants the permission.
 	c.addPostRoute(v1.RouteInviteNewUser, c.HandleInviteNewUser,
-		new(v1.InviteNewUser), permissionAdmin, false)
+		new(v1.InviteNewUser), permissionManageUsers, false)
 	c.addPostRoute(v1.RouteManageUser, c.HandleManageUser, new(v1.ManageUser),
-		permissionAdmin, false)
+		permissionManageUsers, false)
+	c.addPostRoute(v1.RouteSetUserHourlyRate, c.HandleSetUserHourlyRate,
+		new(v1.SetUserHourlyRate), permissionAdmin, false)
+	c.addPostRoute(v1.RouteSetUserRole, c.HandleSetUserRole,
+		new(v1.SetUserRole), permissionAdmin, false)
 	c.addGetRoute(v1.RouteInvoices, c.HandleInvoices,
-		new(v1.Invoices), permissionAdmin, true)
+		new(v1.Invoices), permissionViewInvoices, true)
 	c.addPostRoute(v1.RouteSetInvoiceStatus, c.HandleSetInvoiceStatus,
-		new(v1.SetInvoiceStatus), permissionAdmin, true)
+		new(v1.SetInvoiceStatus), permissionSetInvoiceStatus, true)
 	c.addPostRoute(v1.RouteReviewInvoices, c.HandleReviewInvoices,
-		new(v1.ReviewInvoices), permissionAdmin, true)
+		new(v1.ReviewInvoices), permissionViewInvoices, true)
+	c.addPostRoute(v1.RouteReviewInvoiceLineItem,
+		c.HandleReviewInvoiceLineItem, new(v1.ReviewInvoiceLineItem),
+		permissionReviewInvoices, true)
 	c.addPostRoute(v1.RoutePayInvoices, c.HandlePayInvoices,
-		new(v1.PayInvoices), permissionAdmin, true)
+		new(v1.PayInvoices), permissionPayInvoices, true)
+	c.addGetRoute(v1.RouteMonthlyRate, c.HandleMonthlyRate,
+		new(v1.MonthlyRate), permissionViewInvoices, false)
+	c.addPostRoute(v1.RouteFetchMonthlyRate, c.HandleFetchMonthlyRate,
+		new(v1.FetchMonthlyRate), permissionPayInvoices, false)
+	c.addPostRoute(v1.RouteLockMonthlyRate, c.HandleLockMonthlyRate,
+		new(v1.LockMonthlyRate), permissionPayInvoices, false)
+	c.addPostRoute(v1.RouteReconcileInvoicePayment,
+		c.HandleReconcileInvoicePayment, new(v1.ReconcileInvoicePayment),
+		permissionPayInvoices, true)
+	c.addGetRoute(v1.RouteProposalSpending, c.HandleProposalSpending,
+		new(v1.ProposalSpending), permissionViewInvoices, true)
+	c.addPostRoute(v1.RouteSetProposalBudget, c.HandleSetProposalBudget,
+		new(v1.SetProposalBudget), permissionAdmin, true)
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/hex"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/decred/contractor-mgmt/cmswww/api/v1"
 	"github.com/decred/contractor-mgmt/cmswww/database"
+	"github.com/decred/contractor-mgmt/cmswww/totp"
 )
 
 var (
@@ -147,18 +149,42 @@ func (c *cmswww) ReplaceSessionIfInvalid(w http.ResponseWriter, r *http.Request)
 	return false, nil
 }
 
-// isAdmin returns true if the current session has admin privileges.
-func (c *cmswww) isAdmin(r *http.Request) (bool, error) {
-	user, err := c.GetSessionUser(r)
+// addFailedLoginAttempt records a failed login attempt for the user, and
+// emails them if it caused their account to be locked.
+func (c *cmswww) addFailedLoginAttempt(user *database.User) error {
+	if IsUserLocked(user) {
+		return nil
+	}
+
+	// The attempts start over once a lock expires, and the next lock lasts
+	// longer than the previous one.
+	if user.FailedLoginAttempts >= v1.LoginAttemptsToLockUser {
+		user.FailedLoginAttempts = 0
+	}
+
+	user.FailedLoginAttempts = user.FailedLoginAttempts + 1
+	locked := user.FailedLoginAttempts >= v1.LoginAttemptsToLockUser
+	if locked {
+		user.Lockouts++
+		user.LockedUntil = 0
+		duration := c.lockDuration(user.Lockouts)
+		if duration > 0 {
+			user.LockedUntil = time.Now().Add(duration).Unix()
+		}
+	}
+
+	err := c.db.UpdateUser(user)
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	if user == nil {
-		return false, nil
+	// Check if the user is locked again so we can send an email.
+	if locked {
+		// This is conditional on the email server being setup.
+		return c.emailUserLocked(user.Email, user.LockedUntil)
 	}
 
-	return user.Admin, nil
+	return nil
 }
 
 func (c *cmswww) login(l *v1.Login) loginReplyWithError {
@@ -193,26 +219,11 @@ func (c *cmswww) login(l *v1.Login) loginReplyWithError {
 	err = bcrypt.CompareHashAndPassword(user.HashedPassword,
 		[]byte(l.Password))
 	if err != nil {
-		if !IsUserLocked(user.FailedLoginAttempts) {
-			user.FailedLoginAttempts = user.FailedLoginAttempts + 1
-			err := c.db.UpdateUser(user)
-			if err != nil {
-				return loginReplyWithError{
-					reply: nil,
-					err:   err,
-				}
-			}
-
-			// Check if the user is locked again so we can send an email.
-			if IsUserLocked(user.FailedLoginAttempts) {
-				// This is conditional on the email server being setup.
-				err := c.emailUserLocked(user.Email)
-				if err != nil {
-					return loginReplyWithError{
-						reply: nil,
-						err:   err,
-					}
-				}
+		err := c.addFailedLoginAttempt(user)
+		if err != nil {
+			return loginReplyWithError{
+				reply: nil,
+				err:   err,
 			}
 		}
 
@@ -224,8 +235,15 @@ func (c *cmswww) login(l *v1.Login) loginReplyWithError {
 		}
 	}
 
+	return c.completeLogin(user, l.TOTPCode)
+}
+
+// completeLogin finishes the login of a user who has been authenticated,
+// either by password or by signature: it checks that the user isn't locked
+// and the second factor if it's enabled, then records the login.
+func (c *cmswww) completeLogin(user *database.User, totpCode string) loginReplyWithError {
 	// Check if user is locked due to too many login attempts
-	if IsUserLocked(user.FailedLoginAttempts) {
+	if IsUserLocked(user) {
 		return loginReplyWithError{
 			reply: nil,
 			err: v1.UserError{
@@ -234,10 +252,44 @@ func (c *cmswww) login(l *v1.Login) loginReplyWithError {
 		}
 	}
 
+	// Check the second factor for users who have enabled it. A missing
+	// code is reported separately so that the client can ask for it, and
+	// an invalid one counts as a failed login attempt.
+	if user.TOTPVerified {
+		if totpCode == "" {
+			return loginReplyWithError{
+				reply: nil,
+				err: v1.UserError{
+					ErrorCode: v1.ErrorStatusTOTPCodeRequired,
+				},
+			}
+		}
+
+		step, ok := totp.Validate(user.TOTPSecret, totpCode, time.Now(),
+			user.TOTPLastStep)
+		if !ok {
+			err := c.addFailedLoginAttempt(user)
+			if err != nil {
+				return loginReplyWithError{
+					reply: nil,
+					err:   err,
+				}
+			}
+
+			return loginReplyWithError{
+				reply: nil,
+				err: v1.UserError{
+					ErrorCode: v1.ErrorStatusInvalidTOTPCode,
+				},
+			}
+		}
+		user.TOTPLastStep = step
+	}
+
 	lastLogin := user.LastLogin
-	user.FailedLoginAttempts = 0
+	unlockUser(user)
 	user.LastLogin = time.Now().Unix()
-	err = c.db.UpdateUser(user)
+	err := c.db.UpdateUser(user)
 	if err != nil {
 		return loginReplyWithError{
 			reply: nil,
@@ -298,6 +350,148 @@ func (c *cmswww) HandleLogin(
 	return loginReply.reply, loginReply.err
 }
 
+// HandleLoginChallenge issues a challenge which the user can sign with their
+// active identity to log in without a password. The challenge is stored in
+// the session along with the email, and is issued whether or not the user
+// exists so that it can't be used to find out.
+func (c *cmswww) HandleLoginChallenge(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	lc := req.(*v1.LoginChallenge)
+
+	challenge, err := util.Random(v1.LoginChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	expiry := time.Now().Add(v1.LoginChallengeExpiryTime).Unix()
+
+	session, err := c.getSession(r)
+	if err != nil {
+		return nil, err
+	}
+	session.Values["challenge"] = hex.EncodeToString(challenge)
+	session.Values["challengeemail"] = lc.Email
+	session.Values["challengeexpiry"] = expiry
+	err = session.Save(r, w)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.LoginChallengeReply{
+		Challenge: hex.EncodeToString(challenge),
+		Expiry:    expiry,
+	}, nil
+}
+
+// takeLoginChallenge removes the login challenge from the session, so that
+// it can only be used once, and returns it if it was issued for the given
+// email and hasn't expired.
+func (c *cmswww) takeLoginChallenge(w http.ResponseWriter, r *http.Request, email string) (string, error) {
+	session, err := c.getSession(r)
+	if err != nil {
+		return "", err
+	}
+
+	challenge, _ := session.Values["challenge"].(string)
+	challengeEmail, _ := session.Values["challengeemail"].(string)
+	expiry, _ := session.Values["challengeexpiry"].(int64)
+
+	delete(session.Values, "challenge")
+	delete(session.Values, "challengeemail")
+	delete(session.Values, "challengeexpiry")
+	err = session.Save(r, w)
+	if err != nil {
+		return "", err
+	}
+
+	if challenge == "" || challengeEmail != email ||
+		expiry < time.Now().Unix() {
+		return "", v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidLoginChallenge,
+		}
+	}
+
+	return challenge, nil
+}
+
+func (c *cmswww) loginSignature(ls *v1.LoginSignature, challenge string) loginReplyWithError {
+	// Get user from db.
+	user, err := c.db.GetUserByEmail(ls.Email)
+	if err != nil {
+		if err == database.ErrUserNotFound {
+			return loginReplyWithError{
+				reply: nil,
+				err: v1.UserError{
+					ErrorCode: v1.ErrorStatusInvalidSignature,
+				},
+			}
+		}
+		return loginReplyWithError{
+			reply: nil,
+			err:   err,
+		}
+	}
+
+	// Check that the user is verified.
+	if user.IsVerified() {
+		return loginReplyWithError{
+			reply: nil,
+			err: v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidSignature,
+			},
+		}
+	}
+
+	// Check the signature of the challenge. Errors about the public key
+	// are reported as an invalid signature as well, so that they can't be
+	// used to find out whether the user exists.
+	err = checkPublicKeyAndSignature(user, ls.PublicKey, ls.Signature,
+		challenge)
+	if err != nil {
+		if _, ok := err.(v1.UserError); ok {
+			err = v1.UserError{
+				ErrorCode: v1.ErrorStatusInvalidSignature,
+			}
+		}
+		return loginReplyWithError{
+			reply: nil,
+			err:   err,
+		}
+	}
+
+	return c.completeLogin(user, ls.TOTPCode)
+}
+
+// HandleLoginSignature logs the user in given a signature of the challenge
+// issued by HandleLoginChallenge, made with their active identity.
+func (c *cmswww) HandleLoginSignature(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	ls := req.(*v1.LoginSignature)
+
+	challenge, err := c.takeLoginChallenge(w, r, ls.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	loginReply := c.loginSignature(ls, challenge)
+	if loginReply.err == nil {
+		// Mark user as logged in if there's no error.
+		err := c.setSessionUser(w, r, ls.Email)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return loginReply.reply, loginReply.err
+}
+
 func (c *cmswww) CreateLoginReply(user *database.User, lastLogin int64) (*v1.LoginReply, error) {
 	activeIdentity, ok := database.ActiveIdentityString(user.Identities)
 	if !ok {
@@ -311,6 +505,9 @@ func (c *cmswww) CreateLoginReply(user *database.User, lastLogin int64) (*v1.Log
 		Username:  user.Username,
 		PublicKey: activeIdentity,
 		LastLogin: lastLogin,
+
+		TOTPEnabled: user.TOTPVerified,
+		Roles:       user.Roles,
 	}
 
 	return &reply, nil
@@ -52,7 +52,10 @@ const templateUserLockedResetPasswordRaw = `
 $ cmswwwcli resetpassword {{.Email}}
 	</code></pre>
 </div>
-<div style="margin-top: 20px">
+{{if .UnlockTime}}<div style="margin-top: 20px">
+	Otherwise, your account will be unlocked automatically at {{.UnlockTime}}.
+</div>
+{{end}}<div style="margin-top: 20px">
 	You are receiving this email because someone made too many login attempts
 	 for <span style="font-weight: bold">{{.Email}}</span> on Decred Contractor
 	 Management. If that was not you, please notify the administrators.
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/database"
+	"github.com/decred/contractor-mgmt/cmswww/totp"
+)
+
+const (
+	// totpIssuer is the name under which codes are listed in authenticator
+	// apps.
+	totpIssuer = "cmswww"
+)
+
+// validateTOTPCode checks a code against the user's TOTP secret and records
+// its time step so that it can't be used again.
+func (c *cmswww) validateTOTPCode(user *database.User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(),
+		user.TOTPLastStep)
+	if !ok {
+		return v1.UserError{
+			ErrorCode: v1.ErrorStatusInvalidTOTPCode,
+		}
+	}
+
+	user.TOTPLastStep = step
+	return nil
+}
+
+// HandleEnrollTOTP generates a new TOTP secret for the user. Enrolling again
+// before the secret has been verified replaces it.
+func (c *cmswww) HandleEnrollTOTP(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	if user.TOTPVerified {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusTOTPAlreadyEnabled,
+		}
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	err = c.db.UpdateUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.EnrollTOTPReply{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// HandleVerifyTOTP enables two-factor authentication once the user has shown
+// that they can generate codes from the secret of their enrollment.
+func (c *cmswww) HandleVerifyTOTP(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	vt := req.(*v1.VerifyTOTP)
+
+	if user.TOTPVerified {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusTOTPAlreadyEnabled,
+		}
+	}
+	if user.TOTPSecret == "" {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusTOTPNotEnabled,
+		}
+	}
+
+	err := c.validateTOTPCode(user, vt.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPVerified = true
+	err = c.db.UpdateUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.VerifyTOTPReply{}, nil
+}
+
+// HandleDisableTOTP disables two-factor authentication given a current code.
+// Admins and users with roles can't disable it if the server requires it for
+// them.
+func (c *cmswww) HandleDisableTOTP(
+	req interface{},
+	user *database.User,
+	w http.ResponseWriter,
+	r *http.Request,
+) (interface{}, error) {
+	dt := req.(*v1.DisableTOTP)
+
+	if !user.TOTPVerified {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusTOTPNotEnabled,
+		}
+	}
+	if isPrivilegedUser(user) && c.cfg.AdminTOTP {
+		return nil, v1.UserError{
+			ErrorCode: v1.ErrorStatusTOTPEnrollmentRequired,
+			ErrorContext: []string{"admins and users with roles must use " +
+				"two-factor authentication"},
+		}
+	}
+
+	// An invalid code counts as a failed login attempt, so that a stolen
+	// session can't be used to guess codes.
+	err := c.validateTOTPCode(user, dt.Code)
+	if err != nil {
+		dbErr := c.addFailedLoginAttempt(user)
+		if dbErr != nil {
+			return nil, dbErr
+		}
+		return nil, err
+	}
+
+	resetTOTP(user)
+	err = c.db.UpdateUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.DisableTOTPReply{}, nil
+}
+
+// resetTOTP clears the TOTP settings of a user, which disables two-factor
+// authentication until they enroll again.
+func resetTOTP(user *database.User) {
+	user.TOTPSecret = ""
+	user.TOTPVerified = false
+	user.TOTPLastStep = 0
+}
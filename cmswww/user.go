@@ -16,9 +16,36 @@ import (
 )
 
 // IsUserLocked returns true if the number of failed login attempts exceeds
-// the threshold for locking a user account.
-func IsUserLocked(failedLoginAttempts uint64) bool {
-	return failedLoginAttempts >= v1.LoginAttemptsToLockUser
+// the threshold for locking a user account and the lock hasn't expired.
+func IsUserLocked(user *database.User) bool {
+	if user.FailedLoginAttempts < v1.LoginAttemptsToLockUser {
+		return false
+	}
+
+	return user.LockedUntil == 0 || time.Now().Unix() < user.LockedUntil
+}
+
+// lockDuration returns how long a user is locked for after the given number
+// of consecutive locks. It doubles with each lock, up to the configured
+// maximum; 0 means that the user stays locked until their password is reset
+// or an admin unlocks them.
+func (c *cmswww) lockDuration(lockouts uint64) time.Duration {
+	duration := c.cfg.LockDuration
+	for i := uint64(1); i < lockouts && duration < c.cfg.MaxLockDuration; i++ {
+		duration *= 2
+	}
+	if duration > c.cfg.MaxLockDuration {
+		duration = c.cfg.MaxLockDuration
+	}
+	return duration
+}
+
+// unlockUser clears the failed login attempts of a user, along with the
+// locks which were caused by them.
+func unlockUser(user *database.User) {
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = 0
+	user.Lockouts = 0
 }
 
 // hashPassword hashes the given password string with the default bcrypt cost
@@ -659,7 +686,7 @@ func (c *cmswww) verifyResetPassword(
 	user.ResetPasswordVerificationToken = nil
 	user.ResetPasswordVerificationExpiry = 0
 	user.HashedPassword = hashedPassword
-	user.FailedLoginAttempts = 0
+	unlockUser(user)
 
 	return c.db.UpdateUser(user)
 }
@@ -2,7 +2,6 @@ package main
 
 import (
 	"bytes"
-	"encoding/base64"
 	"encoding/hex"
 	"regexp"
 	"strconv"
@@ -118,29 +117,58 @@ func validateInvoice(ni *v1.SubmitInvoice, user *database.User) error {
 		}
 	}
 
-	data, err := base64.StdEncoding.DecodeString(ni.File.Payload)
+	data, err := verifyFileDigest(&ni.File)
 	if err != nil {
 		return err
 	}
-	digest := util.Digest(data)
 
-	// Validate the string representation of the digest against the signature.
-	if !pk.VerifyMessage([]byte(hex.EncodeToString(digest)), sig) {
+	receipts, err := validateInvoiceAttachments(ni.Attachments)
+	if err != nil {
+		return err
+	}
+
+	// Validate the merkle root of the invoice file and its attachments
+	// against the signature.
+	sortInvoiceAttachments(ni.Attachments)
+	merkleRoot, err := invoiceMerkleRoot(&ni.File, ni.Attachments)
+	if err != nil {
+		return err
+	}
+	if !pk.VerifyMessage([]byte(merkleRoot), sig) {
 		return v1.UserError{
 			ErrorCode: v1.ErrorStatusInvalidSignature,
 		}
 	}
 
+	// Validate the invoice content according to its format.
+	switch ni.File.MIME {
+	case v1.MIMETypeInvoiceCSV:
+		return validateInvoiceCSV(data, ni.Month, ni.Year, user, receipts)
+	case v1.MIMETypeInvoiceJSON:
+		ii, err := decodeInvoiceInput(data)
+		if err != nil {
+			return err
+		}
+
+		return validateInvoiceInput(ii, ni.Month, ni.Year, receipts)
+	default:
+		return v1.UserError{
+			ErrorCode: v1.ErrorStatusUnsupportedMIMEType,
+		}
+	}
+
 	return nil
 }
 
-// Invoices should only be viewable by admins and the users who submit them.
+// Invoices should only be viewable by admins, users with a role which allows
+// it and the users who submit them.
 func validateUserCanSeeInvoice(invoice *v1.InvoiceRecord, user *database.User) error {
 	authorID, err := strconv.ParseUint(invoice.UserID, 10, 64)
 	if err != nil {
 		return err
 	}
-	if user == nil || (!user.Admin && user.ID != authorID) {
+	if user == nil || (!userHasPermission(user, permissionViewInvoices) &&
+		user.ID != authorID) {
 		return v1.UserError{
 			ErrorCode: v1.ErrorStatusInvoiceNotFound,
 		}
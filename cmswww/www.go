@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
 	"os"
@@ -19,6 +20,7 @@ import (
 	"time"
 
 	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
 	"github.com/decred/politeia/politeiad/api/v1/mime"
 	"github.com/decred/politeia/util"
 	"github.com/gorilla/csrf"
@@ -26,8 +28,17 @@ import (
 	"github.com/gorilla/sessions"
 
 	"github.com/decred/contractor-mgmt/cmswww/api/v1"
+	"github.com/decred/contractor-mgmt/cmswww/chain"
+	"github.com/decred/contractor-mgmt/cmswww/chain/dcrrpc"
+	"github.com/decred/contractor-mgmt/cmswww/chain/explorer"
+	"github.com/decred/contractor-mgmt/cmswww/chain/memory"
 	"github.com/decred/contractor-mgmt/cmswww/database"
 	"github.com/decred/contractor-mgmt/cmswww/database/cockroachdb"
+	"github.com/decred/contractor-mgmt/cmswww/rate"
+	"github.com/decred/contractor-mgmt/cmswww/rate/binance"
+	"github.com/decred/contractor-mgmt/cmswww/rate/coingecko"
+	"github.com/decred/contractor-mgmt/cmswww/rate/coinmetrics"
+	"github.com/decred/contractor-mgmt/cmswww/rate/csv"
 )
 
 type permission uint
@@ -35,7 +46,13 @@ type permission uint
 const (
 	permissionPublic permission = iota
 	permissionLogin
-	permissionAdmin
+	permissionAdmin            // Admins only
+	permissionViewInvoices     // Read invoices, reviews and rates
+	permissionViewUsers        // Read the details of other users
+	permissionReviewInvoices   // Review line items
+	permissionSetInvoiceStatus // Approve, reject or mark invoices paid
+	permissionPayInvoices      // Pay invoices and manage the DCR rates
+	permissionManageUsers      // Invite, lock and unlock users
 
 	csrfKeyLength = 32
 
@@ -43,11 +60,15 @@ const (
 	indexFile = "index.md"
 
 	// mdStream* indicate the metadata stream used for various types
-	mdStreamGeneral = 0 // General information for this invoice
-	mdStreamChanges = 1 // Changes to record
-
-	VersionBackendInvoiceMetadata  = 1
-	VersionBackendInvoiceMDChanges = 1
+	mdStreamGeneral         = 0 // General information for this invoice
+	mdStreamChanges         = 1 // Changes to record
+	mdStreamComments        = 2 // Comments on record
+	mdStreamLineItemReviews = 3 // Admin decisions on line items
+
+	VersionBackendInvoiceMetadata       = 2
+	VersionBackendInvoiceMDChanges      = 3
+	VersionBackendInvoiceComment        = 1
+	VersionBackendInvoiceLineItemReview = 1
 )
 
 // cmswww application context.
@@ -60,10 +81,15 @@ type cmswww struct {
 	store *sessions.FilesystemStore
 
 	db             database.Database
+	watcher        chain.Watcher // Used to detect invoice payments
+	rateSources    []rate.Source // Used to compute the monthly DCR/USD rate
 	params         *chaincfg.Params
 	client         *http.Client             // politeiad client
+	identity       *identity.FullIdentity   // Signs payout batches
 	userPubkeys    map[string]string        // [pubkey][userid]
 	polledPayments map[string]polledPayment // [token][polledPayment]
+	pollQuit       chan struct{}            // Closed to stop the payment checker
+	pollWG         sync.WaitGroup           // Payment checker thread
 
 	// Following entries require locks
 	inventoryLoaded bool // Current inventory
@@ -205,11 +231,28 @@ func (c *cmswww) HandlePolicy(
 		MaxUsernameLength:      v1.PolicyMaxUsernameLength,
 		UsernameSupportedChars: v1.PolicyUsernameSupportedChars,
 		ListPageSize:           v1.ListPageSize,
+		MaxCommentLength:       v1.PolicyMaxCommentLength,
 		ValidMIMETypes:         mime.ValidMimeTypes(),
+		AdminTOTPRequired:      c.cfg.AdminTOTP,
 		Invoice: v1.InvoicePolicy{
 			FieldDelimiterChar: v1.PolicyInvoiceFieldDelimiterChar,
 			CommentChar:        v1.PolicyInvoiceCommentChar,
 			Fields:             v1.InvoiceFields,
+			MIMETypes: []string{
+				v1.MIMETypeInvoiceCSV,
+				v1.MIMETypeInvoiceJSON,
+			},
+			FormatVersion: v1.InvoiceFormatVersion,
+			Currency:      v1.PolicyInvoiceCurrency,
+			Currencies:    v1.PolicyInvoiceCurrencies,
+			LineItemKinds: []string{
+				v1.LineItemKind[v1.LineItemKindHourly],
+				v1.LineItemKind[v1.LineItemKindFixed],
+				v1.LineItemKind[v1.LineItemKindMilestone],
+			},
+			AttachmentMIMETypes: v1.PolicyInvoiceAttachmentMIMETypes,
+			MaxAttachments:      v1.PolicyMaxInvoiceAttachments,
+			MaxAttachmentSize:   v1.PolicyMaxInvoiceAttachmentSize,
 		},
 	}, nil
 }
@@ -278,6 +321,7 @@ func _main() error {
 		params:         activeNetParams.Params,
 		userPubkeys:    make(map[string]string),
 		polledPayments: make(map[string]polledPayment),
+		pollQuit:       make(chan struct{}),
 	}
 
 	// Check if this command is being run to fetch the identity.
@@ -293,6 +337,56 @@ func _main() error {
 		return err
 	}
 
+	// Setup the chain watcher used to detect invoice payments.
+	switch c.cfg.ChainWatcher {
+	case chainWatcherDcrRPC:
+		var cert []byte
+		if c.cfg.DcrdRPCCert != "" {
+			cert, err = ioutil.ReadFile(c.cfg.DcrdRPCCert)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.watcher, err = dcrrpc.New(c.cfg.DcrdRPCHost, c.cfg.DcrdRPCUser,
+			c.cfg.DcrdRPCPass, cert)
+		if err != nil {
+			return err
+		}
+	case chainWatcherMemory:
+		log.Warnf("USING THE IN-MEMORY CHAIN WATCHER: no payments will " +
+			"be detected and approved invoices will never be marked as paid")
+		c.watcher = memory.New()
+	default:
+		c.watcher, err = explorer.New(c.params)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Setup the sources of the monthly DCR/USD rate.
+	for _, source := range c.cfg.RateSources {
+		switch source {
+		case rateSourceCoinmetrics:
+			c.rateSources = append(c.rateSources,
+				coinmetrics.New(c.cfg.CoinmetricsURL))
+		case rateSourceBinance:
+			c.rateSources = append(c.rateSources,
+				binance.New(c.cfg.BinanceURL))
+		case rateSourceCoinGecko:
+			c.rateSources = append(c.rateSources,
+				coingecko.New(c.cfg.CoinGeckoURL))
+		case rateSourceCSV:
+			c.rateSources = append(c.rateSources,
+				csv.New(c.cfg.RateCSVFile))
+		}
+	}
+
+	err = c.loadServerIdentity()
+	if err != nil {
+		return err
+	}
+
 	// Setup pubkey-userid map.
 	err = c.InitUserPubkeys()
 	if err != nil {
@@ -355,6 +449,15 @@ func _main() error {
 		return err
 	}
 
+	// Start polling for the payments of invoices which have been approved
+	// and aren't fully paid yet. This is done after the last setup step
+	// which can fail, so that the poller is always stopped before the
+	// database is closed.
+	err = c.initPaymentChecker()
+	if err != nil {
+		return err
+	}
+
 	// Bind to a port and pass our router in
 	listenC := make(chan error)
 	for _, listener := range loadedCfg.Listeners {
@@ -405,6 +508,14 @@ func _main() error {
 		}
 	}
 done:
+	// Let the payments which are being checked finish before the database
+	// is closed.
+	c.stopPaymentChecker()
+	err = c.db.Close()
+	if err != nil {
+		log.Errorf("Close database: %v", err)
+	}
+
 	log.Infof("Exiting")
 	return nil
 }